@@ -0,0 +1,78 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/nuttann/equtils/pkg/eqdb"
+	"gopkg.in/yaml.v2"
+)
+
+// 'config' holds the locations of the quest data and item DB. This uses the
+// same configuration file as the "collectstoweb" command, so other fields in
+// the file are ignored.
+type config struct {
+	QuestsFile string // File with exp - zone - quest - item ID mappings
+	ItemDBLoc  string // DB location info (currently file name)
+}
+
+func readConfig(confFile string) (confData config) {
+	// Read configuration data.
+	buf, err := ioutil.ReadFile(confFile)
+	if err != nil {
+		log.Fatalf("error: Configuration file - %v", err)
+	}
+	err = yaml.Unmarshal(buf, &confData)
+	if err != nil {
+		log.Fatalf("error: Configuration file - %v", err)
+	}
+	return
+}
+
+func main() {
+	confPtr := flag.String("conf", "", "Configuration file to get questsfile and itemdbloc from.")
+	questsPtr := flag.String("quests", "", "Quest data file. (Overrides questsfile)")
+	itemDBPtr := flag.String("itemdb", "", "Item DB file. (Overrides itemdbloc)")
+	flag.Parse()
+
+	var conf config
+	if *confPtr != "" {
+		conf = readConfig(*confPtr)
+	}
+	if *questsPtr != "" {
+		conf.QuestsFile = *questsPtr
+	}
+	if *itemDBPtr != "" {
+		conf.ItemDBLoc = *itemDBPtr
+	}
+	if conf.QuestsFile == "" {
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	quests, err := eqdb.ReadQuests(conf.QuestsFile)
+	if err != nil {
+		log.Fatalf("error: Quest data file - %v", err)
+	}
+	var itemDB *eqdb.Items // Item names are not checked without a DB.
+	if conf.ItemDBLoc != "" {
+		items := eqdb.OpenItemDB(conf.ItemDBLoc)
+		itemDB = &items
+	}
+	problems := eqdb.ValidateQuests(quests, itemDB)
+	for _, problem := range problems {
+		fmt.Printf("%s: %v\n", conf.QuestsFile, problem)
+	}
+	if len(problems) > 0 {
+		fmt.Printf("%d problems found.\n", len(problems))
+		os.Exit(1)
+	}
+	fmt.Println("No problems found.")
+}
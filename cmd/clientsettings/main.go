@@ -0,0 +1,299 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nuttann/equtils/pkg/eqfile"
+	"gopkg.in/yaml.v2"
+)
+
+// 'config' holds the settings files to compare.
+type config struct {
+	Files     []settingsFile
+	BackupDir string // Where backups are written (Optional, default same folder)
+}
+
+// settingsFile is an eqclient.ini of an install or the UI file of a
+// character ("{TOON}_{SERVER}.ini").
+type settingsFile struct {
+	Name string
+	INI  string
+}
+
+func readConfig(confFile string) (confData config) {
+	// Read configuration data.
+	buf, err := ioutil.ReadFile(confFile)
+	if err != nil {
+		log.Fatalf("error: Configuration file - %v", err)
+	}
+	err = yaml.Unmarshal(buf, &confData)
+	if err != nil {
+		log.Fatalf("error: Configuration file - %v", err)
+	}
+	for n, f := range confData.Files {
+		if f.Name == "" || f.INI == "" {
+			log.Fatalf("error: Configuration file - files[%d] needs a name and ini", n+1)
+		}
+	}
+	return
+}
+
+// findINI will return the settings file with the name (ignoring case).
+func findINI(conf config, name string) string {
+	for _, f := range conf.Files {
+		if strings.EqualFold(f.Name, name) {
+			return f.INI
+		}
+	}
+	log.Fatalf("error: No file named \"%s\" in the configuration file", name)
+	return ""
+}
+
+// readINI will return the settings file with the name.
+func readINI(conf config, name string) *eqfile.INI {
+	ini, err := eqfile.ReadINI(findINI(conf, name))
+	if err != nil {
+		log.Fatalf("error: Settings file - %v", err)
+	}
+	return ini
+}
+
+// selected returns true if the section is in the list (ignoring case). All
+// sections are selected if the list is empty.
+func selected(sections []string, name string) bool {
+	if len(sections) == 0 {
+		return true
+	}
+	for _, s := range sections {
+		if strings.EqualFold(s, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// sectionNames will return the selected section names of both files, those
+// of the first file first.
+func sectionNames(a *eqfile.INI, b *eqfile.INI, sections []string) []string {
+	var names []string
+	for _, ini := range []*eqfile.INI{a, b} {
+		for _, s := range ini.Sections {
+			if !selected(sections, s.Name) {
+				continue
+			}
+			found := false
+			for _, name := range names {
+				if strings.EqualFold(name, s.Name) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				names = append(names, s.Name)
+			}
+		}
+	}
+	return names
+}
+
+// keys will return the keys of the section in both files, those of the first
+// file first.
+func keys(a *eqfile.INI, b *eqfile.INI, section string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, ini := range []*eqfile.INI{a, b} {
+		s := ini.Section(section)
+		if s == nil {
+			continue
+		}
+		for _, key := range s.Keys() {
+			if !seen[strings.ToLower(key)] {
+				seen[strings.ToLower(key)] = true
+				result = append(result, key)
+			}
+		}
+	}
+	return result
+}
+
+// diffSettings will print the settings that differ between the files in the
+// sections. It returns the number of differences.
+func diffSettings(a *eqfile.INI, aName string, b *eqfile.INI, bName string, sections []string) int {
+	diffs := 0
+	fmt.Printf("==== %s / %s\n", aName, bName)
+	for _, section := range sectionNames(a, b, sections) {
+		for _, key := range keys(a, b, section) {
+			aValue, aOK := a.Get(section, key)
+			bValue, bOK := b.Get(section, key)
+			switch {
+			case !bOK:
+				fmt.Printf("    [%s] %s - only %s \"%s\"\n", section, key, aName, aValue)
+			case !aOK:
+				fmt.Printf("    [%s] %s - only %s \"%s\"\n", section, key, bName, bValue)
+			case aValue != bValue:
+				fmt.Printf("    [%s] %s - %s \"%s\" - %s \"%s\"\n", section, key, aName, aValue, bName, bValue)
+			default:
+				continue
+			}
+			diffs++
+		}
+	}
+	if diffs == 0 {
+		fmt.Println("    Same")
+	}
+	return diffs
+}
+
+// checkSettings will print the problems with the key bindings of the file. It
+// returns the number of problems.
+func checkSettings(ini *eqfile.INI, name string) int {
+	problems := ini.CheckKeyMaps()
+	fmt.Printf("==== %s - %d problems\n", name, len(problems))
+	for _, p := range problems {
+		fmt.Printf("    %v\n", p)
+	}
+	return len(problems)
+}
+
+// applySettings will set every key of the sections of the source in the
+// target. Keys only in the target are not changed. It returns the number of
+// values changed.
+func applySettings(from *eqfile.INI, to *eqfile.INI, sections []string) int {
+	changed := 0
+	for _, section := range from.Sections {
+		if !selected(sections, section.Name) {
+			continue
+		}
+		for _, key := range section.Keys() {
+			value, _ := section.Get(key)
+			if old, ok := to.Get(section.Name, key); ok && old == value {
+				continue
+			}
+			to.Set(section.Name, key, value)
+			changed++
+		}
+	}
+	return changed
+}
+
+// newProblems will return the problems in after that are not in before.
+func newProblems(before []error, after []error) []error {
+	seen := make(map[string]bool)
+	for _, p := range before {
+		seen[p.Error()] = true
+	}
+	var result []error
+	for _, p := range after {
+		if !seen[p.Error()] {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+func main() {
+	confPtr := flag.String("conf", "", "Configuration file. (Required)")
+	checkPtr := flag.String("check", "", "Check the key bindings of this file. (Default all files)")
+	diffPtr := flag.String("diff", "", "Compare the settings of this file with the -with file.")
+	withPtr := flag.String("with", "", "File to compare with.")
+	applyPtr := flag.String("apply", "", "Apply the -sections settings of this file to the -to files.")
+	toPtr := flag.String("to", "", "Files to apply to. (E.g., \"Test,Gallin\")")
+	sectionsPtr := flag.String("sections", "", "Only use these sections. (E.g., \"KeyMaps,Defaults\")")
+	flag.Parse()
+
+	if *confPtr == "" {
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+	conf := readConfig(*confPtr)
+	var sections []string
+	if *sectionsPtr != "" {
+		for _, s := range strings.Split(*sectionsPtr, ",") {
+			sections = append(sections, strings.TrimSpace(s))
+		}
+	}
+
+	switch {
+	case *diffPtr != "":
+		if *withPtr == "" {
+			log.Fatalf("error: -diff needs -with")
+		}
+		if diffSettings(readINI(conf, *diffPtr), *diffPtr, readINI(conf, *withPtr), *withPtr, sections) > 0 {
+			os.Exit(1)
+		}
+	case *applyPtr != "":
+		if *toPtr == "" {
+			log.Fatalf("error: -apply needs -to")
+		}
+		if len(sections) == 0 {
+			log.Fatalf("error: -apply needs -sections")
+		}
+		from := readINI(conf, *applyPtr)
+		now := time.Now()
+		failed := false
+		// Check all targets before any file is changed.
+		var names []string
+		var targets []*eqfile.INI
+		for _, name := range strings.Split(*toPtr, ",") {
+			name = strings.TrimSpace(name)
+			if findINI(conf, name) == findINI(conf, *applyPtr) {
+				log.Fatalf("error: Cannot apply %s to itself", name)
+			}
+			names = append(names, name)
+			targets = append(targets, readINI(conf, name))
+		}
+		for n, to := range targets {
+			name, fname := names[n], findINI(conf, names[n])
+			before := to.CheckKeyMaps()
+			count := applySettings(from, to, sections)
+			if problems := newProblems(before, to.CheckKeyMaps()); len(problems) > 0 {
+				// Keep going with the other files.
+				fmt.Printf("error: Not applied to %s - it would have these problems:\n", name)
+				for _, p := range problems {
+					fmt.Printf("    %v\n", p)
+				}
+				failed = true
+				continue
+			}
+			if count == 0 {
+				fmt.Printf("No changes to %s\n", name)
+				continue
+			}
+			bak, err := eqfile.BackupFile(fname, conf.BackupDir, now)
+			if err != nil {
+				log.Fatalf("error: Backing up %s - %v", fname, err)
+			}
+			if err = to.WriteFile(fname); err != nil {
+				log.Fatalf("error: Writing %s - %v (Backup in %s)", fname, err, bak)
+			}
+			fmt.Printf("Changed %d settings of %s (Backup in %s)\n", count, name, bak)
+		}
+		if failed {
+			os.Exit(1)
+		}
+	default:
+		if *checkPtr != "" {
+			findINI(conf, *checkPtr) // Report an unknown name.
+		}
+		problems := 0
+		for _, f := range conf.Files {
+			if *checkPtr != "" && !strings.EqualFold(f.Name, *checkPtr) {
+				continue
+			}
+			problems += checkSettings(readINI(conf, f.Name), f.Name)
+		}
+		if problems > 0 {
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nuttann/equtils/pkg/eqfile"
+)
+
+// checkConfig will cross-check the house configuration against the quest data
+// and the real-estate files. Errors are problems that would leave sections
+// missing from the output:
+//   - Expansion names not in the quest data.
+//   - Zone names not in the quest data for the expansion.
+//   - House addresses not in the house's real-estate file.
+//
+// Warnings are things that may be intended:
+//   - Quests not configured for any house.
+//   - Zones configured for more than one house.
+//
+// Nothing is changed, so the real-estate files are read directly rather than
+// with readRE, which adds their names to the item DB.
+func checkConfig(state intState, conf config) (errs []string, warnings []string) {
+	var expNames []string
+	for _, questExp := range *state.QuestData {
+		expNames = append(expNames, questExp.Name)
+	}
+	covered := make(map[string][]string) // Houses for each "expansion / zone"
+	reFiles := make(map[string][]eqfile.REItem)
+	for n, house := range conf.Houses {
+		where := fmt.Sprintf("houses[%d] (%s)", n+1, house.Address)
+		for _, houseExp := range house.Expansions {
+			found := false
+			for _, questExp := range *state.QuestData {
+				if houseExp.Name != questExp.Name {
+					continue
+				}
+				found = true
+				if houseExp.Zones == nil {
+					for _, questZone := range questExp.Zones {
+						key := questExp.Name + " / " + questZone.Name
+						covered[key] = append(covered[key], house.Address)
+					}
+					break
+				}
+				var zoneNames []string
+				for _, questZone := range questExp.Zones {
+					zoneNames = append(zoneNames, questZone.Name)
+				}
+				for _, loczone := range houseExp.Zones {
+					if !contains(zoneNames, loczone) {
+						errs = append(errs, fmt.Sprintf("%s: unknown zone \"%s\" in %s%s",
+							where, loczone, houseExp.Name, didYouMean(loczone, zoneNames)))
+						continue
+					}
+					key := questExp.Name + " / " + loczone
+					covered[key] = append(covered[key], house.Address)
+				}
+				break
+			}
+			if !found {
+				errs = append(errs, fmt.Sprintf("%s: unknown expansion \"%s\"%s",
+					where, houseExp.Name, didYouMean(houseExp.Name, expNames)))
+			}
+		}
+		var addresses []string
+		reData, ok := reFiles[house.Fname]
+		if !ok {
+			var err error
+			if reData, err = eqfile.ReadRE(house.Fname); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", where, err))
+				continue
+			}
+			reFiles[house.Fname] = reData
+		}
+		for _, entry := range reData {
+			if entry.RELoc == "Plot" && !contains(addresses, entry.REName) {
+				addresses = append(addresses, entry.REName)
+			}
+		}
+		if !contains(addresses, house.Address) {
+			errs = append(errs, fmt.Sprintf("%s: address not found in %s%s",
+				where, house.Fname, didYouMean(house.Address, addresses)))
+		}
+	}
+	for _, questExp := range *state.QuestData {
+		var missing []string
+		for _, questZone := range questExp.Zones {
+			key := questExp.Name + " / " + questZone.Name
+			houses := covered[key]
+			switch {
+			case len(houses) == 0:
+				missing = append(missing, fmt.Sprintf("%s (%d quests)", key, len(questZone.Quests)))
+			case len(houses) > 1:
+				warnings = append(warnings, fmt.Sprintf("%s is configured for %d houses - %s",
+					key, len(houses), strings.Join(houses, "; ")))
+			}
+		}
+		if len(missing) > 0 && len(missing) == len(questExp.Zones) {
+			warnings = append(warnings, fmt.Sprintf("%s is not configured for any house", questExp.Name))
+			continue
+		}
+		for _, m := range missing {
+			warnings = append(warnings, fmt.Sprintf("%s is not configured for any house", m))
+		}
+	}
+	return errs, warnings
+}
+
+// contains returns true if the string is in the list.
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// didYouMean will return a suggestion of the closest candidate to the name or
+// "" if none is close. The suggestion is formatted to add to a message.
+func didYouMean(name string, candidates []string) string {
+	best := ""
+	bestDist := len(name)/3 + 2 // Allow some typos, more for longer names.
+	lname := strings.ToLower(name)
+	for _, candidate := range candidates {
+		lcand := strings.ToLower(candidate)
+		if lname == lcand || (lname != "" && strings.Contains(lcand, lname)) {
+			return fmt.Sprintf(" - did you mean \"%s\"?", candidate)
+		}
+		if dist := editDistance(lname, lcand); dist < bestDist {
+			best = candidate
+			bestDist = dist
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(" - did you mean \"%s\"?", best)
+}
+
+// editDistance returns the Levenshtein distance between the strings.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, minInt(cur[j-1]+1, prev[j-1]+cost))
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// minInt returns the smaller of the integers.
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
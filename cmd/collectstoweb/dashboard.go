@@ -0,0 +1,198 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"html/template"
+	"log"
+	"sort"
+	"strconv"
+)
+
+// The dashboard is an overall view of the collection across all configured
+// houses. Unlike the house reports, it covers every quest in the quests file
+// whether or not it is configured for a house.
+
+// dashboard holds the counts across all houses along with the items missing
+// from every house (the shopping list) and the items found in more than one
+// house.
+type dashboard struct {
+	Stocked    int           `json:"stocked"` // Quest items in at least one house
+	Missing    int           `json:"missing"` // Quest items not in any house
+	Total      int           `json:"total"`
+	Expansions []dashExp     `json:"expansions"`
+	Shopping   []dashItem    `json:"shoppinglist"` // Missing items
+	Duplicates []dashDupItem `json:"duplicates"`   // Items in multiple houses
+}
+
+// dashExp holds the counts for an expansion.
+type dashExp struct {
+	Name    string     `json:"name"`
+	Stocked int        `json:"stocked"`
+	Missing int        `json:"missing"`
+	Total   int        `json:"total"`
+	Zones   []dashZone `json:"zones"`
+}
+
+// dashZone holds the counts for a zone.
+type dashZone struct {
+	Name       string `json:"name"`
+	Stocked    int    `json:"stocked"`
+	Missing    int    `json:"missing"`
+	Total      int    `json:"total"`
+	Duplicated int    `json:"duplicated"` // Quest items in more than one house
+}
+
+// dashItem identifies a quest item and where it is in the quest data.
+type dashItem struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	Expansion string `json:"expansion"`
+	Zone      string `json:"zone"`
+	Quest     string `json:"quest"`
+}
+
+// dashDupItem is a quest item that is stored in more than one house.
+type dashDupItem struct {
+	dashItem
+	Houses []dashHouseCount `json:"houses"`
+}
+
+// dashHouseCount is the count of an item in a house.
+type dashHouseCount struct {
+	Address string `json:"address"`
+	Count   int    `json:"count"`
+}
+
+// buildDashboard will total the quest items across all houses.
+func buildDashboard(state intState, stock []houseStock) (dashboard, error) {
+	var d dashboard
+	for _, questExp := range *state.QuestData {
+		de := dashExp{Name: questExp.Name}
+		for _, questZone := range questExp.Zones {
+			dz := dashZone{Name: questZone.Name}
+			for _, quest := range questZone.Quests {
+				ids, err := quest.IDs()
+				if err != nil {
+					return d, fmt.Errorf("Quest file bad int range - %v", err)
+				}
+				for _, id := range ids {
+					item := dashItem{
+						ID:        id,
+						Name:      state.ItemDB.Name(id),
+						Expansion: questExp.Name,
+						Zone:      questZone.Name,
+						Quest:     quest.Name,
+					}
+					if item.Name == "" {
+						item.Name = "???" // Use this if name is not known.
+					}
+					var houses []dashHouseCount
+					for _, hs := range stock {
+						if count := hs.Items[id].Count; count > 0 {
+							houses = append(houses, dashHouseCount{
+								Address: hs.Address,
+								Count:   count,
+							})
+						}
+					}
+					dz.Total++
+					switch {
+					case len(houses) == 0:
+						dz.Missing++
+						d.Shopping = append(d.Shopping, item)
+					case len(houses) > 1:
+						dz.Duplicated++
+						d.Duplicates = append(d.Duplicates, dashDupItem{
+							dashItem: item,
+							Houses:   houses,
+						})
+					}
+				}
+			}
+			dz.Stocked = dz.Total - dz.Missing
+			de.Zones = append(de.Zones, dz)
+			de.Stocked += dz.Stocked
+			de.Missing += dz.Missing
+			de.Total += dz.Total
+		}
+		d.Expansions = append(d.Expansions, de)
+		d.Stocked += de.Stocked
+		d.Missing += de.Missing
+		d.Total += de.Total
+	}
+	sort.SliceStable(d.Duplicates, func(i, j int) bool {
+		return d.Duplicates[i].Name < d.Duplicates[j].Name
+	})
+	return d, nil
+}
+
+// dashTemplateDef is the html/template definition for the dashboard page. All
+// data is escaped for HTML.
+const dashTemplateDef = `<!DOCTYPE html>
+<html>
+    <head><title>{{.Title}} - Dashboard</title></head>
+    <body>
+	    <h1>{{.Title}} - Dashboard</h1>
+		{{with .Dashboard}}
+		<p>Quest items stocked in at least one house = {{.Stocked}} / {{.Total}}
+			(Missing = {{.Missing}})</p>
+		<table>
+		<tr><th>Expansion / Zone</th><th>Stocked</th><th>Missing</th><th>Total</th><th>Duplicated</th></tr>
+		{{range .Expansions}}
+		<tr><th>{{.Name}}</th><th>{{.Stocked}}</th><th>{{.Missing}}</th><th>{{.Total}}</th><th></th></tr>
+		{{range .Zones}}
+		<tr><td>{{.Name}}</td><td>{{.Stocked}}</td><td>{{.Missing}}</td><td>{{.Total}}</td><td>{{.Duplicated}}</td></tr>
+		{{end}}
+		{{end}}
+		</table>
+		<h2>Items in more than one house</h2>
+		<ul>
+		{{range .Duplicates}}
+		<li>{{.Name}} ({{.Expansion}} / {{.Zone}} / {{.Quest}})<ul>
+		{{range .Houses}}<li>{{.Address}} ({{.Count}})</li>{{end}}
+		</ul></li>
+		{{end}}
+		</ul>
+		<h2>Shopping list</h2>
+		<p>Quest items not found in any house.</p>
+		<ul>
+		{{range .Shopping}}
+		<li>{{.Name}} [{{.ID}}] ({{.Expansion}} / {{.Zone}} / {{.Quest}})</li>
+		{{end}}
+		</ul>
+		{{end}}
+	</body>
+</html>
+`
+
+// writeDashboardHTML will output the dashboard as an HTML page.
+func writeDashboardHTML(w *bufio.Writer, report collectionReport) error {
+	dashTemplate, err := template.New("dashboard").Parse(dashTemplateDef)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return dashTemplate.Execute(w, report)
+}
+
+// writeShoppingList will output the items missing from all houses as plain
+// text, grouped by expansion, zone, and quest.
+func writeShoppingList(w *bufio.Writer, report collectionReport) error {
+	var last string
+	for _, item := range report.Dashboard.Shopping {
+		group := item.Expansion + " / " + item.Zone + " / " + item.Quest
+		if group != last {
+			if last != "" {
+				w.WriteString("\n")
+			}
+			w.WriteString(group + "\n")
+			last = group
+		}
+		w.WriteString("    " + item.Name + " (" + strconv.Itoa(item.ID) + ")\n")
+	}
+	return nil
+}
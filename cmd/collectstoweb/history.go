@@ -0,0 +1,279 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"html/template"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/nuttann/equtils/pkg/eqdb"
+)
+
+// The stock history keeps a snapshot of the counts in each house whenever
+// they change. The counts are the ones in the real-estate files, before any
+// reservations or hand-outs in the ledger, so hand-outs can be checked
+// against the items that actually left a house.
+
+// changeReport lists the changes in stock between the last two snapshots.
+type changeReport struct {
+	Since  time.Time      `json:"since"` // Time of the previous snapshot
+	Time   time.Time      `json:"time"`  // Time of the latest snapshot
+	Houses []houseChanges `json:"houses"`
+}
+
+// houseChanges holds the changes in stock for a house.
+type houseChanges struct {
+	Address string       `json:"address"`
+	Added   []itemChange `json:"added,omitempty"`   // Items not in the house before
+	Removed []itemChange `json:"removed,omitempty"` // Items no longer in the house
+	Changed []itemChange `json:"changed,omitempty"` // Items with a different count
+}
+
+// itemChange is a change in the count of an item.
+type itemChange struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Before int    `json:"before"`
+	After  int    `json:"after"`
+}
+
+// itemName will return the name of the item or "???" if it is not known.
+func itemName(state intState, id int) string {
+	if name := state.ItemDB.Name(id); name != "" {
+		return name
+	}
+	return "???" // Use this if name is not known.
+}
+
+// stockSnapshot will return a snapshot of the stored counts of the stock.
+func stockSnapshot(stock []houseStock) eqdb.StockSnapshot {
+	snap := eqdb.StockSnapshot{
+		Time:   time.Now().Truncate(time.Second),
+		Houses: make(map[string]map[int]int),
+	}
+	for _, hs := range stock {
+		counts := make(map[int]int)
+		for id, item := range hs.Items {
+			counts[id] = item.Stored
+		}
+		snap.Houses[hs.Address] = counts
+	}
+	return snap
+}
+
+// saveHistory will add the snapshot of the report to the history and save it.
+// Nothing is done if there is no history.
+func saveHistory(state intState, report collectionReport) error {
+	if state.History == nil {
+		return nil
+	}
+	state.History.Add(report.snapshot)
+	return state.History.Close()
+}
+
+// buildChanges will return the changes between the last two snapshots as if
+// the snapshot was added to the history. The history is not changed, so the
+// snapshot is only saved by saveHistory. Running again without any changes
+// still reports the last changes. Nil is returned if there is no history or
+// only one snapshot.
+func buildChanges(state intState, snap eqdb.StockSnapshot) *changeReport {
+	if state.History == nil {
+		return nil
+	}
+	history := *state.History
+	n := len(history.Snapshots)
+	history.Snapshots = history.Snapshots[:n:n] // Add must not write to the shared array.
+	history.Add(snap)
+	n = len(history.Snapshots)
+	if n < 2 {
+		return nil
+	}
+	before, after := history.Snapshots[n-2], history.Snapshots[n-1]
+	report := &changeReport{Since: before.Time, Time: after.Time}
+	for _, change := range eqdb.DiffSnapshots(before, after) {
+		hc := len(report.Houses) - 1
+		if hc < 0 || report.Houses[hc].Address != change.Address {
+			report.Houses = append(report.Houses, houseChanges{Address: change.Address})
+			hc++
+		}
+		ic := itemChange{
+			ID:     change.ID,
+			Name:   itemName(state, change.ID),
+			Before: change.Before,
+			After:  change.After,
+		}
+		switch {
+		case change.Before == 0:
+			report.Houses[hc].Added = append(report.Houses[hc].Added, ic)
+		case change.After == 0:
+			report.Houses[hc].Removed = append(report.Houses[hc].Removed, ic)
+		default:
+			report.Houses[hc].Changed = append(report.Houses[hc].Changed, ic)
+		}
+	}
+	for _, hc := range report.Houses {
+		sortChanges(hc.Added)
+		sortChanges(hc.Removed)
+		sortChanges(hc.Changed)
+	}
+	return report
+}
+
+// sortChanges will sort the changes by item name and then by ID.
+func sortChanges(changes []itemChange) {
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Name != changes[j].Name {
+			return changes[i].Name < changes[j].Name
+		}
+		return changes[i].ID < changes[j].ID
+	})
+}
+
+// writeChanges will output the stock changes as plain text. This is suitable
+// for posting as a "new stock" announcement.
+func writeChanges(w *bufio.Writer, report collectionReport) error {
+	changes := report.Changes
+	if changes == nil {
+		_, err := fmt.Fprintln(w, "No earlier stock snapshot to compare with.")
+		return err
+	}
+	fmt.Fprintf(w, "Stock changes from %s to %s\n", changes.Since.Local().Format("2006-01-02 15:04"),
+		changes.Time.Local().Format("2006-01-02 15:04"))
+	if len(changes.Houses) == 0 {
+		fmt.Fprintln(w, "No changes.")
+	}
+	for _, hc := range changes.Houses {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "====", hc.Address)
+		for _, ic := range hc.Added {
+			fmt.Fprintf(w, "New - %s (%d)\n", ic.Name, ic.After)
+		}
+		for _, ic := range hc.Changed {
+			fmt.Fprintf(w, "Changed - %s (%d -> %d)\n", ic.Name, ic.Before, ic.After)
+		}
+		for _, ic := range hc.Removed {
+			fmt.Fprintf(w, "Removed - %s (%d)\n", ic.Name, ic.Before)
+		}
+	}
+	return nil
+}
+
+// itemHistoryRow is a row of the item history table.
+type itemHistoryRow struct {
+	Time   string
+	Counts []int // Count for each house (0 if not in the house)
+	Total  int
+}
+
+// itemHistoryTable is the history of an item in all houses.
+type itemHistoryTable struct {
+	ID      int
+	Name    string
+	Houses  []string // House addresses in the order of the counts
+	Rows    []itemHistoryRow
+	Message string // Shown if there is no history
+}
+
+// buildItemHistory will return the history table for the item. Only houses
+// that held the item at some time are included.
+func buildItemHistory(state intState, id int) itemHistoryTable {
+	table := itemHistoryTable{ID: id, Name: itemName(state, id)}
+	if state.History == nil {
+		table.Message = "No stock history is configured."
+		return table
+	}
+	history := state.History.ItemHistory(id)
+	if len(history) == 0 {
+		table.Message = "The item is not in any stock snapshot."
+		return table
+	}
+	seen := make(map[string]bool)
+	for _, entry := range history {
+		for address := range entry.Counts {
+			if !seen[address] {
+				seen[address] = true
+				table.Houses = append(table.Houses, address)
+			}
+		}
+	}
+	sort.Strings(table.Houses)
+	for _, entry := range history {
+		row := itemHistoryRow{Time: entry.Time.Local().Format("2006-01-02 15:04")}
+		for _, address := range table.Houses {
+			row.Counts = append(row.Counts, entry.Counts[address])
+			row.Total += entry.Counts[address]
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table
+}
+
+// printItemHistory will list the history of the item on the terminal with a
+// line for each change.
+func printItemHistory(table itemHistoryTable) {
+	fmt.Printf("==== %s (%d)\n", table.Name, table.ID)
+	if table.Message != "" {
+		fmt.Println(table.Message)
+		return
+	}
+	for _, row := range table.Rows {
+		line := fmt.Sprintf("%s - total %d", row.Time, row.Total)
+		for i, address := range table.Houses {
+			if row.Counts[i] > 0 {
+				line += fmt.Sprintf(" - %s: %d", address, row.Counts[i])
+			}
+		}
+		fmt.Println(line)
+	}
+}
+
+// historyTemplateDef is the html/template definition for the item history
+// page. All data is escaped for HTML.
+const historyTemplateDef = `<!DOCTYPE html>
+<html>
+    <head><title>{{.Name}} - History</title></head>
+    <body>
+	    <h1>{{.Name}} ({{.ID}}) - History</h1>
+		{{if .Message}}<p>{{.Message}}</p>{{else}}
+		<table border="1">
+		<tr><th>Time</th>{{range .Houses}}<th>{{.}}</th>{{end}}<th>Total</th></tr>
+		{{range .Rows}}<tr><td>{{.Time}}</td>{{range .Counts}}<td>{{.}}</td>{{end}}<td>{{.Total}}</td></tr>
+		{{end}}
+		</table>
+		{{end}}
+	</body>
+</html>
+`
+
+// writeItemHistoryHTML will output the history table of an item as an HTML
+// page.
+func writeItemHistoryHTML(w *bufio.Writer, table itemHistoryTable) error {
+	historyTemplate, err := template.New("history").Parse(historyTemplateDef)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return historyTemplate.Execute(w, table)
+}
+
+// findItem will return the item ID for an item name or ID. The name must
+// match exactly one item in the item DB (ignoring case).
+func findItem(state intState, text string) (int, error) {
+	if id, err := strconv.Atoi(text); err == nil {
+		return id, nil
+	}
+	ids := state.ItemDB.FindName(text)
+	switch len(ids) {
+	case 0:
+		return 0, fmt.Errorf("no item named \"%s\" in the item DB", text)
+	case 1:
+		return ids[0], nil
+	}
+	return 0, fmt.Errorf("several items named \"%s\" - use an ID", text)
+}
@@ -0,0 +1,85 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"html/template"
+	"log"
+	"strconv"
+)
+
+// writeZone will output all collection quests / items for a zone with a quest
+// being expandable. All names and notes are escaped for HTML.
+func writeZone(w *bufio.Writer, zone zoneReport) {
+	w.WriteString("<li>" + template.HTMLEscapeString(zone.Name) + "<ul>")
+	for _, quest := range zone.Quests {
+		w.WriteString("<details><summary>" + template.HTMLEscapeString(quest.Name) + " (" + strconv.Itoa(quest.Have) + "/" + strconv.Itoa(quest.Total) + ") ")
+		if quest.Note != "" {
+			w.WriteString(" - " + template.HTMLEscapeString(quest.Note))
+		}
+		w.WriteString("\n</summary>\n<ul>\n")
+		for _, item := range quest.Items {
+			if item.Reserved > 0 {
+				w.WriteString(fmt.Sprint("<li>", template.HTMLEscapeString(item.Name), " (", item.Count, ", ", item.Reserved, " reserved)</li>\n"))
+			} else {
+				w.WriteString(fmt.Sprint("<li>", template.HTMLEscapeString(item.Name), " (", item.Count, ")</li>\n"))
+			}
+		}
+		w.WriteString("</ul>\n</details>\n")
+	}
+	w.WriteString("</ul></li>\n")
+}
+
+// writeHouseHTML will output the HTML for the quests in the passed house. The
+// address and expansion names are escaped for HTML.
+func writeHouseHTML(w *bufio.Writer, house houseReport) {
+	w.WriteString("<h2>" + template.HTMLEscapeString(house.Address) + "</h2><ul>\n")
+	for _, exp := range house.Expansions {
+		w.WriteString("<li>" + template.HTMLEscapeString(exp.Name) + "<ul>\n")
+		for _, zone := range exp.Zones {
+			writeZone(w, zone)
+		}
+		w.WriteString("</ul></li>\n")
+	}
+	w.WriteString("</ul><p>Summary - Items = " + strconv.Itoa(house.Have) + " / " + strconv.Itoa(house.Total) + "</p>\n")
+}
+
+// headTemplateDef is the html/template definition for the beginning of the
+// HTML output. HTMLTitle is treated as text and escaped for HTML. HTMLIntro is
+// treated as raw HTML, so the user can embed links and such.
+const headTemplateDef = `<!DOCTYPE html>
+<html>
+    <head><title>{{.HTMLTitle}}</title></head>
+    <body>
+	    <h1>{{.HTMLTitle}}</h1>
+		{{unescape .HTMLIntro}}
+		<p>Click quest to expand items.
+			The counts for items are in parentheses.</p>`
+
+// writeHTML controls the overall HTML output.
+func writeHTML(w *bufio.Writer, conf config, report collectionReport) error {
+	// Output beginning HTML. HTML escape all except the 'HTMLIntro" config.
+	mainTemplate, err := template.New("page").Funcs(template.FuncMap{
+		"unescape": func(s string) template.HTML {
+			return template.HTML(s)
+		},
+	}).Parse(headTemplateDef)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = mainTemplate.Execute(w, conf)
+	if err != nil {
+		return err
+	}
+	// Output house data.
+	for _, house := range report.Houses {
+		writeHouseHTML(w, house)
+	}
+	// Output end of HTML doc.
+	_, err = w.WriteString("</body></html>\n")
+	return err
+}
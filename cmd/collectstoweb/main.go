@@ -6,16 +6,13 @@ package main
 
 import (
 	"bufio"
-	"bytes"
 	"flag"
 	"fmt"
-	"html/template"
 	"io/ioutil"
 	"log"
 	"os"
-	"strconv"
+	"time"
 
-	"github.com/brianholland99/intlist"
 	"github.com/nuttann/equtils/pkg/eqdb"
 	"github.com/nuttann/equtils/pkg/eqfile"
 	"gopkg.in/yaml.v2"
@@ -26,59 +23,58 @@ import (
 // includes the nested House definition that configures which quests'
 // collection items are stored in each house.
 type config struct {
-	QuestsFile string  // File with exp - zone - quest - item ID mappings
-	ItemDBLoc  string  // DB location info (currently file name)
-	HTMLOut    string  // Where to write output
-	HTMLTitle  string  // Single line title for header and <h1> tag
-	HTMLIntro  string  // HTML to include between the <body> tag and the quest info
-	Houses     []House // House collection configuration
+	QuestsFile      string   // File with exp - zone - quest - item ID mappings
+	ItemDBLoc       string   // DB location info (currently file name)
+	HTMLOut         string   // Where to write HTML output
+	JSONOut         string   // Where to write JSON report (Optional)
+	CSVOut          string   // Where to write CSV report (Optional)
+	DashboardOut    string   // Where to write dashboard across houses (Optional)
+	ShoppingListOut string   // Where to write items missing from houses (Optional)
+	StackReportOut  string   // Where to write stack report (Optional)
+	KeepThreshold   int      // Count over which items are surplus (0 = none)
+	HouseSlots      int      // Storage slots in each house (Optional)
+	LedgerLoc       string   // Reservation ledger location (Optional)
+	HistoryLoc      string   // Stock history location (Optional)
+	ChangesOut      string   // Where to write stock changes (Optional)
+	ProgressLoc     string   // Character progress location (Optional)
+	NeedsOut        string   // Where to write items needed by characters (Optional)
+	HTMLTitle       string   // Single line title for header and <h1> tag
+	HTMLIntro       string   // HTML to include between the <body> tag and the quest info
+	Houses          []House  // House collection configuration
+	Name            string   // Profile name (Only used in profiles)
+	IndexOut        string   // Where to write index of profile pages (Optional)
+	Profiles        []config // Profiles sharing the quest data and item DB (Optional)
 }
 
 // 'intState' holds internal state needed throughout the program. This includes
-// I/O and database handles.
+// database handles and the real-estate files already read.
 type intState struct {
-	ItemDB    *eqdb.Items   // Handle to open DB (set when opening ItemDBLoc)
-	QuestData *[]QuestExp   // Handle to quest data
-	Out       *os.File      // HTML output to close
-	Buf       *bufio.Writer // HTML output writer
+	ItemDB    *eqdb.Items                // Handle to open DB (set when opening ItemDBLoc)
+	QuestData *[]eqdb.QuestExp           // Handle to quest data
+	REData    map[string][]eqfile.REItem // Real-estate data by file name
+	Ledger    *eqdb.Ledger               // Reservation ledger (nil if not configured)
+	History   *eqdb.StockHistory         // Stock history (nil if not configured)
+	Progress  *eqdb.Progress             // Character progress (nil if not configured)
 }
 
 // itemInfo collects counts for an item in a house while examining a realestate
 // dump.
 type itemInfo struct {
-	Name   string
-	Count  int // Total count of that item
-	Stacks int // Number of slots with this item
+	Name     string
+	Count    int // Total count of that item (Less any reserved or handed out)
+	Stacks   int // Number of slots with this item
+	Reserved int // Number of that item with pending reservations
+	Stored   int // Count in the real-estate file
 }
 
 // itemMap holds the counts for all items in a house.
 type itemMap map[int]itemInfo
 
-// There are similarities between generic quest data and specifiic house
-// configuration fields. The names are prefixed with Quest and House
-// respectively. These two sets of hierarchical structures will be merged and
-// processed to produce the results for the output. These are filled directly
-// from the YAML data files.
-
-// QuestInfo holds info for a specific collection quest.
-type QuestInfo struct {
-	Name string // Quest name (E.g., "Fear in Pieces")
-	Ids  string // EQ Item IDs contained in quest (E.g., "500-506, 519")
-	Note string // Notes about this collection set (E.g, "Drop from undead")
-}
-
-// QuestZone holds collection quests for a zone.
-type QuestZone struct {
-	Name   string      // Zone name (E.g., "Shard's Landing")
-	Quests []QuestInfo // All collection quests in this zone.
-}
-
-// QuestExp holds the zones for that expansion. This is the top-level quest
-// structure. Expansions -> Zones -> Quests.
-type QuestExp struct {
-	Name  string      // Expansion name (E.g., "Rain of Fear")
-	Zones []QuestZone // All zones in expansion with collection quests.
-}
+// There are similarities between generic quest data (eqdb.QuestExp) and
+// specific house configuration fields. The house configuration names are
+// prefixed with House. These two sets of hierarchical structures will be
+// merged and processed to produce the results for the output. These are filled
+// directly from the YAML data files.
 
 // HouseExp describes which expansions and zones within that expansion are
 // contained in a house. The Name string here and the Name strings in the Zones
@@ -93,14 +89,16 @@ type HouseExp struct {
 type House struct {
 	Fname      string // Path/File name (E.g., "Nuttann_cazic-RealEstate.txt")
 	Address    string // Property string (From the "/output realestate" file.)
+	Slots      int    // Storage slots (Optional - overrides HouseSlots)
 	Expansions []HouseExp
 }
 
 // Function setup will read the configuration file and perform setup. This
-// includes validating the data and reading data sources.
+// includes validating the data and reading the data sources shared by all
+// profiles.
 //
 // Note: teardown() needs to be called to save any accumulated info such as new
-// item data and to flush and close output.
+// item data.
 func setup(confFile string) (intState, config) {
 	// Read configuration data.
 	var conf config
@@ -113,51 +111,63 @@ func setup(confFile string) (intState, config) {
 	if err != nil {
 		log.Fatalf("error: Configuration file - %v", err)
 	}
-	questData := readQuests(conf.QuestsFile)
+	questData, err := eqdb.ReadQuests(conf.QuestsFile)
+	if err != nil {
+		log.Fatalf("error: Quest data file - %v", err)
+	}
 	state.QuestData = &questData
 	itemDB := eqdb.OpenItemDB(conf.ItemDBLoc)
 	state.ItemDB = &itemDB
-	state.Out, err = os.Create(conf.HTMLOut)
-	if err != nil {
-		log.Fatalf("error: Opening output file - %v", err)
-	}
-	state.Buf = bufio.NewWriter(state.Out)
+	state.REData = make(map[string][]eqfile.REItem)
 	return state, conf
 }
 
 // Function teardown will cleanup data files.
 func teardown(state intState) {
 	state.ItemDB.Close() // Updates if anything was changed.
-	// Finish HTML output.
-	state.Buf.Flush()
-	state.Out.Close()
 }
 
-// readQuests reads a questfile and populates []QuestExp.
-func readQuests(questsFile string) (questData []QuestExp) {
-	buf, err := ioutil.ReadFile(questsFile)
+// readLedger will read the reservation ledger into the state. The state is not
+// changed if the ledger cannot be read.
+func readLedger(state *intState, conf config) error {
+	ledger, err := eqdb.OpenLedger(conf.LedgerLoc)
 	if err != nil {
-		log.Fatalf("error: Quest data file - %v", err)
+		return fmt.Errorf("Ledger file - %v", err)
 	}
-	err = yaml.Unmarshal(buf, &questData)
+	state.Ledger = &ledger
+	return nil
+}
+
+// readRE will return the data from a real-estate file. Each file is only read
+// once, even if it holds several houses. The item DB is updated with the names
+// in the file when it is read. A file that cannot be read is not kept, so it
+// is read again next time (E.g., after EQ finishes writing it).
+func readRE(state intState, fname string) ([]eqfile.REItem, error) {
+	if reData, ok := state.REData[fname]; ok {
+		return reData, nil
+	}
+	reData, err := eqfile.ReadRE(fname)
 	if err != nil {
-		log.Fatalf("error: Quest data file - %v", err)
+		return nil, err
 	}
-	return
+	for _, entry := range reData {
+		state.ItemDB.SetName(entry.ID, entry.ItemName)
+	}
+	state.REData[fname] = reData
+	return reData, nil
 }
 
 // getStoredItemData will return items from Real Estate dump for the given
 // property. This only pulls out 'Stored' items since collection items
 // are not placeable.
-func getStoredItemData(house House, itemDB *eqdb.Items) itemMap {
-	houseData, err := eqfile.ReadRE(house.Fname)
+func getStoredItemData(state intState, house House) (itemMap, error) {
+	houseData, err := readRE(state, house.Fname)
 	if err != nil {
-		log.Fatalf("error: Reading house file - %v", err)
+		return nil, err
 	}
 	items := make(itemMap)
 	for _, entry := range houseData {
 		id := entry.ID
-		itemDB.SetName(id, entry.ItemName)
 		if entry.RELoc != "Plot" || entry.Status != "Stored" {
 			continue
 		}
@@ -170,160 +180,250 @@ func getStoredItemData(house House, itemDB *eqdb.Items) itemMap {
 				Count:  count + items[id].Count,
 				Name:   items[id].Name,
 				Stacks: items[id].Stacks + 1,
+				Stored: count + items[id].Stored,
 			}
 		} else {
 			items[id] = itemInfo{
 				Count:  count,
 				Name:   entry.ItemName,
 				Stacks: 1,
+				Stored: count,
 			}
 		}
 	}
-	return items
+	return items, nil
 }
 
-// writeZone will output all collection quests / items for a zone with a quest
-// being expandable.
-// Returns:
-//    haveItems - # of unique quest items for this zone present in house.
-//    totalItems - total # of unique quest items for this zone.
-func writeZone(state intState, house House, zone QuestZone, items itemMap) (haveItems int, totalItems int) {
-	state.Buf.WriteString("<li>" + zone.Name + "<ul>")
-	for _, quest := range zone.Quests {
-		ids, err := intlist.Parse(quest.Ids)
-		if err != nil {
-			log.Fatalf("error: Quest file bad int range - %v", err)
+func main() {
+	// Get configuration file name.
+	confFile := flag.String("conf", "", "Configuration file. (Required)")
+	watchFlag := flag.Bool("watch", false, "Keep running and rewrite outputs when house files change.")
+	interval := flag.Duration("interval", 5*time.Second, "How often to check files in watch or serve mode.")
+	checkOnly := flag.Bool("check", false, "Only check the configuration. Warnings are also treated as errors.")
+	requestFile := flag.String("request", "", "Print request mails for the items in this wish list file instead of writing outputs.")
+	serveAddr := flag.String("serve", "", "Serve pages over HTTP on this address (E.g., \":8080\") instead of writing outputs.")
+	historyItem := flag.String("history", "", "Print the stock history of this item (name or ID) instead of writing outputs.")
+	profileName := flag.String("profile", "", "Only use this profile. (Default is all profiles)")
+	flag.Parse()
+	if *confFile == "" {
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	// Use configuration to setup DBs.
+	state, conf := setup(*confFile)
+	defer teardown(state) // Save any new item data at end.
+
+	top := conf // Top level of the configuration for the index page.
+	profiles := profileConfigs(conf)
+	var runs []profileRun
+	for _, p := range profiles {
+		if *profileName == "" || p.Name == *profileName {
+			runs = append(runs, profileRun{openProfile(state, p), p})
 		}
-		have := 0
-		// Collect quest HTML in Buffer so that it can be added after summary
-		// that includes counts. (Browsers vary display order if summary is at
-		// end of details.)
-		var buf bytes.Buffer
-		for _, id := range ids {
-			name := state.ItemDB.Name(id)
-			if name == "" {
-				name = "???" // Use this if name is not known.
-			}
-			if items[id].Stacks > 1 {
-				fmt.Println("Multiple stacks -", items[id].Name, "-", house.Address)
-			}
-			count := items[id].Count
-			if count > 0 {
-				delete(items, id)
-				have++ // Keep count of unique items for this quest in the house.
-			}
-			str := fmt.Sprint("<li>", name, " (", count, ")</li>\n")
-			buf.WriteString(str)
+	}
+	if len(runs) == 0 {
+		log.Fatalf("error: No profile named \"%s\"", *profileName)
+	}
+
+	// Check the configuration before producing anything.
+	errCount, warningCount := 0, 0
+	for _, run := range runs {
+		prefix := ""
+		if run.conf.Name != "" {
+			prefix = run.conf.Name + ": "
+		}
+		errs, warnings := checkConfig(run.state, run.conf)
+		for _, warning := range warnings {
+			fmt.Println("warning:", prefix+warning)
 		}
-		state.Buf.WriteString("<details><summary>" + quest.Name + " (" + strconv.Itoa(have) + "/" + strconv.Itoa(len(ids)) + ") ")
-		if quest.Note != "" {
-			state.Buf.WriteString(" - " + quest.Note)
+		for _, err := range errs {
+			fmt.Println("error:", prefix+err)
 		}
-		state.Buf.WriteString("\n</summary>\n<ul>\n" + buf.String() + "</ul>\n</details>\n")
-		// Add counts to totals for zone.
-		totalItems += len(ids)
-		haveItems += have
+		errCount += len(errs)
+		warningCount += len(warnings)
+	}
+	if *checkOnly {
+		if errCount+warningCount > 0 {
+			fmt.Printf("%d errors and %d warnings found.\n", errCount, warningCount)
+			os.Exit(1)
+		}
+		fmt.Println("No problems found.")
+		return
+	}
+	if errCount > 0 {
+		log.Fatalf("error: %d configuration errors found. Nothing was written.", errCount)
 	}
-	state.Buf.WriteString("</ul></li>\n")
-	return
-}
-
-// writeHouseHTML will output the HTML for the quests in the passed house.
-func writeHouseHTML(state intState, house House) {
-	state.Buf.WriteString("<h2>" + house.Address + "</h2><ul>\n")
-	totalItems := 0
-	haveItems := 0
 
-	items := getStoredItemData(house, state.ItemDB) // 'items' is filtered to only have "Stored" items.
-	for _, houseExp := range house.Expansions {
-		for _, questExp := range *state.QuestData {
-			if houseExp.Name == questExp.Name { // Found the quest data for this expansion.
-				state.Buf.WriteString("<li>" + questExp.Name + "<ul>\n")
-				if houseExp.Zones == nil {
-					// Do all zones in this expansion.
-					for _, questZone := range questExp.Zones {
-						have, total := writeZone(state, house, questZone, items)
-						totalItems += total
-						haveItems += have
-					}
-				} else {
-					// Do specific zones listed in order listed.
-					for _, loczone := range houseExp.Zones {
-						// Find the zone in quests.
-						for _, questZone := range questExp.Zones {
-							if loczone == questZone.Name {
-								have, total := writeZone(state, house, questZone, items)
-								totalItems += total
-								haveItems += have
-								break // Handled matching zone.
-							}
-						}
-					}
-				}
-				state.Buf.WriteString("</ul></li>\n")
-				break // Handled matching expansion.
-			}
+	if len(runs) > 1 && (*serveAddr != "" || *historyItem != "" || *requestFile != "") {
+		log.Fatalf("error: Use -profile to choose a profile for -serve, -history, or -request")
+	}
+	state, conf = runs[0].state, runs[0].conf
+	if *serveAddr != "" {
+		serve(state, conf, *serveAddr, *interval)
+		return
+	}
+	if *historyItem != "" {
+		id, err := findItem(state, *historyItem)
+		if err != nil {
+			log.Fatalf("error: %v", err)
+		}
+		printItemHistory(buildItemHistory(state, id))
+		return
+	}
+	if *requestFile != "" {
+		w := bufio.NewWriter(os.Stdout)
+		err := writeRequestMail(w, state, conf, *requestFile)
+		w.Flush()
+		if err != nil {
+			log.Fatalf("error: Wish list - %v", err)
 		}
+		return
 	}
-	state.Buf.WriteString("</ul><p>Summary - Items = " + strconv.Itoa(haveItems) + " / " + strconv.Itoa(totalItems) + "</p>\n")
-	if len(items) > 0 {
-		fmt.Println("====== Extra items in -", house.Address)
-		for _, item := range items {
-			fmt.Println(item.Name, " (", item.Count, ")")
+	for _, run := range runs {
+		if run.conf.HTMLOut == "" && run.conf.JSONOut == "" && run.conf.CSVOut == "" &&
+			run.conf.DashboardOut == "" && run.conf.ShoppingListOut == "" &&
+			run.conf.StackReportOut == "" && run.conf.ChangesOut == "" && run.conf.NeedsOut == "" {
+			log.Fatalf("error: Configuration file - no outputs given%s", profileSuffix(run.conf))
 		}
-		fmt.Println()
 	}
-	return
+	for _, run := range runs {
+		if run.conf.Name != "" {
+			fmt.Println("======== Profile -", run.conf.Name)
+		}
+		report, err := buildReport(run.state, run.conf)
+		if err != nil {
+			log.Fatalf("error: %v", err)
+		}
+		if err = writeOutputs(run.conf, report); err != nil {
+			log.Fatalf("error: %v", err)
+		}
+		// Only once the outputs were written, so a failed run can be repeated.
+		if err = saveHistory(run.state, report); err != nil {
+			log.Fatalf("error: Saving stock history - %v", err)
+		}
+	}
+	if top.IndexOut != "" {
+		err := writeFiles([]output{{top.IndexOut, func(w *bufio.Writer) error {
+			return writeIndexHTML(w, top, profiles)
+		}}})
+		if err != nil {
+			log.Fatalf("error: %v", err)
+		}
+	}
+	if *watchFlag {
+		state.ItemDB.Close() // Save any new item data before waiting.
+		watch(runs, *interval)
+	}
 }
 
-// headTemplateDef is the html/template definition for the beginning of the
-// HTML output. HTMLTitle is treated as text and escaped for HTML. HTMLIntro is
-// treated as raw HTML, so the user can embed links and such.
-const headTemplateDef = `<!DOCTYPE html>
-<html>
-    <head><title>{{.HTMLTitle}}</title></head>
-    <body>
-	    <h1>{{.HTMLTitle}}</h1>
-		{{unescape .HTMLIntro}}
-		<p>Click quest to expand items.
-			The counts for items are in parentheses.</p>`
-
-// writeHTML controls the overall HTML output.
-func writeHTML(state intState, conf config) {
-	// Output beginning HTML. HTML escape all except the 'HTMLIntro" config.
-	mainTemplate, err := template.New("page").Funcs(template.FuncMap{
-		"unescape": func(s string) template.HTML {
-			return template.HTML(s)
-		},
-	}).Parse(headTemplateDef)
-	if err != nil {
-		log.Fatal(err)
+// profileSuffix will return the profile name formatted to add to a message,
+// or "" if the configuration has no profiles.
+func profileSuffix(conf config) string {
+	if conf.Name == "" {
+		return ""
 	}
-	err = mainTemplate.Execute(state.Buf, conf)
-	if err != nil {
-		log.Fatal(err)
-	}
-	// Process and output house data.
+	return " for profile " + conf.Name
+}
+
+// houseFiles will return the real-estate files of all configured houses. Each
+// file is only listed once.
+func houseFiles(conf config) []string {
+	var fnames []string
+	seen := make(map[string]bool)
 	for _, house := range conf.Houses {
-		writeHouseHTML(state, house)
+		if !seen[house.Fname] {
+			seen[house.Fname] = true
+			fnames = append(fnames, house.Fname)
+		}
 	}
-	// Output end of HTML doc.
-	state.Buf.WriteString("</body></html>\n")
-	return
+	return fnames
 }
 
-func main() {
-	// Get configuration file name.
-	confFile := flag.String("conf", "", "Configuration file. (Required)")
-	flag.Parse()
-	if *confFile == "" {
-		flag.PrintDefaults()
-		os.Exit(1)
+// watchedFiles will return all files to check for changes in watch and serve
+// modes. This is the real-estate files, the ledger, and the character
+// progress.
+func watchedFiles(conf config) []string {
+	fnames := houseFiles(conf)
+	if conf.LedgerLoc != "" {
+		fnames = append(fnames, conf.LedgerLoc)
+	}
+	if conf.ProgressLoc != "" {
+		fnames = append(fnames, conf.ProgressLoc)
 	}
+	return fnames
+}
 
-	// Use configuration to setup DBs and outputs.
-	state, conf := setup(*confFile)
-	defer teardown(state) // Save any output and close files at end.
+// reloadChanged will arrange for the changed files to be read again. If the
+// ledger or progress cannot be read, the error is printed and the previous one
+// is kept.
+func reloadChanged(state *intState, conf config, changed []string) {
+	for _, fname := range changed {
+		if fname == conf.LedgerLoc {
+			if err := readLedger(state, conf); err != nil {
+				fmt.Printf("error: %v - keeping the previous ledger\n", err)
+			}
+			continue
+		}
+		if fname == conf.ProgressLoc {
+			if err := readProgress(state, conf); err != nil {
+				fmt.Printf("error: %v - keeping the previous progress\n", err)
+			}
+			continue
+		}
+		delete(state.REData, fname) // Read again when building report.
+	}
+}
 
-	writeHTML(state, conf)
+// watch will poll the real-estate files of all houses and the ledger and
+// rewrite the outputs of each profile whenever any of its files change. Only
+// the changed files are read again. If a file cannot be read (E.g., EQ is
+// still writing it) or an output cannot be written, the error is printed and
+// the previous outputs are left in place. This never returns.
+func watch(runs []profileRun, interval time.Duration) {
+	var fnames []string
+	for _, run := range runs {
+		for _, fname := range watchedFiles(run.conf) {
+			if !contains(fnames, fname) {
+				fnames = append(fnames, fname)
+			}
+		}
+	}
+	watcher := eqfile.NewWatcher(fnames)
+	fmt.Println("Watching", len(fnames), "files for changes.")
+	for {
+		time.Sleep(interval)
+		changed := watcher.Changed()
+		if len(changed) == 0 {
+			continue
+		}
+		for _, fname := range changed {
+			fmt.Println("Changed -", fname)
+		}
+		for n := range runs {
+			run := &runs[n]
+			var mine []string
+			for _, fname := range watchedFiles(run.conf) {
+				if contains(changed, fname) {
+					mine = append(mine, fname)
+				}
+			}
+			if len(mine) == 0 {
+				continue
+			}
+			reloadChanged(&run.state, run.conf, mine)
+			report, err := buildReport(run.state, run.conf)
+			if err == nil {
+				err = writeOutputs(run.conf, report)
+			}
+			if err != nil {
+				fmt.Printf("error: %v%s - keeping the previous outputs\n", err, profileSuffix(run.conf))
+				continue
+			}
+			if err = saveHistory(run.state, report); err != nil {
+				fmt.Printf("error: Saving stock history%s - %v\n", profileSuffix(run.conf), err)
+			}
+		}
+		runs[0].state.ItemDB.Close() // Updates if anything was changed.
+	}
 }
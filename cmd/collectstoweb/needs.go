@@ -0,0 +1,129 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"html/template"
+	"log"
+
+	"github.com/nuttann/equtils/pkg/eqdb"
+)
+
+// The needs view lists, for each character in the progress file, the stored
+// quest items they have not collected yet. This is used to offer items to the
+// people who actually need them. Like the dashboard, it covers every quest in
+// the quests file.
+
+// toonNeeds holds the stored items still needed by a character.
+type toonNeeds struct {
+	Name  string       `json:"name"`
+	Items []neededItem `json:"items"`
+}
+
+// neededItem is a quest item needed by a character and the houses holding it.
+type neededItem struct {
+	dashItem
+	Houses []dashHouseCount `json:"houses"`
+}
+
+// readProgress will read the character progress into the state. The state is
+// not changed if the progress cannot be read.
+func readProgress(state *intState, conf config) error {
+	progress, err := eqdb.OpenProgress(conf.ProgressLoc)
+	if err != nil {
+		return fmt.Errorf("Progress file - %v", err)
+	}
+	state.Progress = &progress
+	return nil
+}
+
+// buildNeeds will list the stored quest items each character still needs.
+// Items of completed quests are not needed. Nil is returned if there is no
+// progress file.
+func buildNeeds(state intState, stock []houseStock) ([]toonNeeds, error) {
+	if state.Progress == nil {
+		return nil, nil
+	}
+	var needs []toonNeeds
+	for _, toon := range state.Progress.Toons {
+		tn := toonNeeds{Name: toon.Name}
+		for _, questExp := range *state.QuestData {
+			for _, questZone := range questExp.Zones {
+				for _, quest := range questZone.Quests {
+					if toon.HasQuest(questExp.Name, questZone.Name, quest.Name) {
+						continue
+					}
+					ids, err := quest.IDs()
+					if err != nil {
+						return nil, fmt.Errorf("Quest file bad int range - %v", err)
+					}
+					for _, id := range ids {
+						if toon.HasItem(id) {
+							continue
+						}
+						var houses []dashHouseCount
+						for _, hs := range stock {
+							if count := hs.Items[id].Count; count > 0 {
+								houses = append(houses, dashHouseCount{
+									Address: hs.Address,
+									Count:   count,
+								})
+							}
+						}
+						if len(houses) == 0 {
+							continue // Nothing to offer.
+						}
+						tn.Items = append(tn.Items, neededItem{
+							dashItem: dashItem{
+								ID:        id,
+								Name:      itemName(state, id),
+								Expansion: questExp.Name,
+								Zone:      questZone.Name,
+								Quest:     quest.Name,
+							},
+							Houses: houses,
+						})
+					}
+				}
+			}
+		}
+		needs = append(needs, tn)
+	}
+	return needs, nil
+}
+
+// needsTemplateDef is the html/template definition for the needs page. All
+// data is escaped for HTML.
+const needsTemplateDef = `<!DOCTYPE html>
+<html>
+    <head><title>{{.Title}} - Needs</title></head>
+    <body>
+	    <h1>{{.Title}} - Needs</h1>
+		{{range .Needs}}
+		<h2>{{.Name}}</h2>
+		{{if .Items}}
+		<ul>
+		{{range .Items}}<li>{{.Name}} - {{.Expansion}} / {{.Zone}} / {{.Quest}}
+			({{range $i, $h := .Houses}}{{if $i}}; {{end}}{{$h.Address}}: {{$h.Count}}{{end}})</li>
+		{{end}}
+		</ul>
+		{{else}}
+		<p>No stored items needed.</p>
+		{{end}}
+		{{end}}
+	</body>
+</html>
+`
+
+// writeNeedsHTML will output the needs of each character as an HTML page.
+func writeNeedsHTML(w *bufio.Writer, report collectionReport) error {
+	needsTemplate, err := template.New("needs").Parse(needsTemplateDef)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return needsTemplate.Execute(w, report)
+}
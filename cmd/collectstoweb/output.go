@@ -0,0 +1,150 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/nuttann/equtils/pkg/eqfile"
+)
+
+// output is a configured output file and the function that writes it.
+type output struct {
+	Fname string
+	Write func(w *bufio.Writer) error
+}
+
+// writeOutputs will write the report to each output configured. All outputs
+// are written to temporary files first. The original files are only replaced
+// once all were written, so a failure leaves the previous outputs in place.
+func writeOutputs(conf config, report collectionReport) error {
+	var outputs []output
+	if conf.HTMLOut != "" {
+		outputs = append(outputs, output{conf.HTMLOut, func(w *bufio.Writer) error {
+			return writeHTML(w, conf, report)
+		}})
+	}
+	if conf.JSONOut != "" {
+		outputs = append(outputs, output{conf.JSONOut, func(w *bufio.Writer) error {
+			return writeJSON(w, report)
+		}})
+	}
+	if conf.CSVOut != "" {
+		outputs = append(outputs, output{conf.CSVOut, func(w *bufio.Writer) error {
+			return writeCSV(w, report)
+		}})
+	}
+	if conf.DashboardOut != "" {
+		outputs = append(outputs, output{conf.DashboardOut, func(w *bufio.Writer) error {
+			return writeDashboardHTML(w, report)
+		}})
+	}
+	if conf.ShoppingListOut != "" {
+		outputs = append(outputs, output{conf.ShoppingListOut, func(w *bufio.Writer) error {
+			return writeShoppingList(w, report)
+		}})
+	}
+	if conf.StackReportOut != "" {
+		outputs = append(outputs, output{conf.StackReportOut, func(w *bufio.Writer) error {
+			return writeStacksHTML(w, report)
+		}})
+	}
+	if conf.ChangesOut != "" {
+		outputs = append(outputs, output{conf.ChangesOut, func(w *bufio.Writer) error {
+			return writeChanges(w, report)
+		}})
+	}
+	if conf.NeedsOut != "" {
+		outputs = append(outputs, output{conf.NeedsOut, func(w *bufio.Writer) error {
+			return writeNeedsHTML(w, report)
+		}})
+	}
+	return writeFiles(outputs)
+}
+
+// writeFiles will write each output to a temporary file and then replace the
+// original files once all were written.
+func writeFiles(outputs []output) error {
+	var temps []string
+	for _, out := range outputs {
+		temp, err := eqfile.WriteTemp(out.Fname, out.Write)
+		if err != nil {
+			for _, t := range temps {
+				os.Remove(t)
+			}
+			return fmt.Errorf("Writing output file %s - %v", out.Fname, err)
+		}
+		temps = append(temps, temp)
+	}
+	for n, out := range outputs {
+		err := os.Rename(temps[n], out.Fname)
+		if err != nil {
+			for _, t := range temps[n:] {
+				os.Remove(t)
+			}
+			return fmt.Errorf("Replacing output file %s - %v", out.Fname, err)
+		}
+	}
+	return nil
+}
+
+// writeJSON will output the whole report as indented JSON.
+func writeJSON(w *bufio.Writer, report collectionReport) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(report)
+}
+
+// csvHeader is the first line of the CSV output. Each following line is either
+// a quest item ("quest") or a stored item not in the configured quests
+// ("extra"). The expansion, zone, quest, have, and total columns are empty for
+// extra items.
+var csvHeader = []string{"type", "house", "expansion", "zone", "quest",
+	"have", "total", "id", "name", "count", "stacks", "reserved"}
+
+// writeCSV will output the report flattened to one line per item.
+func writeCSV(w *bufio.Writer, report collectionReport) error {
+	out := csv.NewWriter(w)
+	err := out.Write(csvHeader)
+	if err != nil {
+		return err
+	}
+	for _, house := range report.Houses {
+		for _, exp := range house.Expansions {
+			for _, zone := range exp.Zones {
+				for _, quest := range zone.Quests {
+					for _, item := range quest.Items {
+						err = out.Write([]string{"quest", house.Address,
+							exp.Name, zone.Name, quest.Name,
+							strconv.Itoa(quest.Have), strconv.Itoa(quest.Total),
+							strconv.Itoa(item.ID), item.Name,
+							strconv.Itoa(item.Count), strconv.Itoa(item.Stacks),
+							strconv.Itoa(item.Reserved)})
+						if err != nil {
+							return err
+						}
+					}
+				}
+			}
+		}
+		for _, item := range house.Extras {
+			err = out.Write([]string{"extra", house.Address, "", "", "", "", "",
+				strconv.Itoa(item.ID), item.Name,
+				strconv.Itoa(item.Count), strconv.Itoa(item.Stacks),
+				strconv.Itoa(item.Reserved)})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
@@ -0,0 +1,179 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"html/template"
+	"log"
+	"path/filepath"
+
+	"github.com/nuttann/equtils/pkg/eqdb"
+)
+
+// Profiles let one configuration file hold several sets of houses and outputs,
+// such as one for each server. All profiles share the quest data and item DB.
+// A configuration without profiles is treated as a single unnamed profile.
+
+// profileRun holds a profile along with its state.
+type profileRun struct {
+	state intState
+	conf  config
+}
+
+// profileConfigs will return the profiles in the configuration. The quest
+// data and item DB are always taken from the top level. The title, intro, keep
+// threshold, and house slots are taken from the top level if not given in a
+// profile. Houses, outputs, and the ledger, history, and progress files can
+// only be given in the profiles, since they would otherwise be ignored.
+func profileConfigs(conf config) []config {
+	if len(conf.Profiles) == 0 {
+		return []config{conf}
+	}
+	if len(conf.Houses) > 0 {
+		log.Fatalf("error: Configuration file - houses must be given in each profile when there are profiles")
+	}
+	for _, top := range []struct{ Key, Value string }{
+		{"htmlout", conf.HTMLOut}, {"jsonout", conf.JSONOut}, {"csvout", conf.CSVOut},
+		{"dashboardout", conf.DashboardOut}, {"shoppinglistout", conf.ShoppingListOut},
+		{"stackreportout", conf.StackReportOut}, {"changesout", conf.ChangesOut},
+		{"needsout", conf.NeedsOut}, {"ledgerloc", conf.LedgerLoc},
+		{"historyloc", conf.HistoryLoc}, {"progressloc", conf.ProgressLoc},
+	} {
+		if top.Value != "" {
+			log.Fatalf("error: Configuration file - %s must be given in each profile when there are profiles", top.Key)
+		}
+	}
+	var profiles []config
+	seen := make(map[string]bool)
+	for n, p := range conf.Profiles {
+		if p.Name == "" {
+			log.Fatalf("error: Configuration file - profiles[%d] has no name", n+1)
+		}
+		if seen[p.Name] {
+			log.Fatalf("error: Configuration file - profile name \"%s\" used more than once", p.Name)
+		}
+		seen[p.Name] = true
+		if len(p.Profiles) > 0 {
+			log.Fatalf("error: Configuration file - profile \"%s\" cannot hold profiles", p.Name)
+		}
+		p.QuestsFile = conf.QuestsFile
+		p.ItemDBLoc = conf.ItemDBLoc
+		if p.HTMLTitle == "" {
+			p.HTMLTitle = conf.HTMLTitle
+		}
+		if p.HTMLIntro == "" {
+			p.HTMLIntro = conf.HTMLIntro
+		}
+		if p.KeepThreshold == 0 {
+			p.KeepThreshold = conf.KeepThreshold
+		}
+		if p.HouseSlots == 0 {
+			p.HouseSlots = conf.HouseSlots
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles
+}
+
+// openProfile will return the state for the profile. The quest data, item DB,
+// and real-estate data are shared with the given state. The ledger, progress,
+// and stock history are read for the profile.
+func openProfile(state intState, conf config) intState {
+	state.Ledger = nil
+	state.Progress = nil
+	state.History = nil
+	if conf.LedgerLoc != "" {
+		if err := readLedger(&state, conf); err != nil {
+			log.Fatalf("error: %v", err)
+		}
+	}
+	if conf.ProgressLoc != "" {
+		if err := readProgress(&state, conf); err != nil {
+			log.Fatalf("error: %v", err)
+		}
+	}
+	if conf.HistoryLoc != "" {
+		history, err := eqdb.OpenStockHistory(conf.HistoryLoc)
+		if err != nil {
+			log.Fatalf("error: Stock history file - %v", err)
+		}
+		state.History = &history
+	}
+	return state
+}
+
+// indexLink is a link to one of the pages of a profile.
+type indexLink struct {
+	Label string
+	Href  string
+}
+
+// indexEntry holds the links to the pages of a profile.
+type indexEntry struct {
+	Name  string
+	Title string
+	Links []indexLink
+}
+
+// buildIndex will return the links to the outputs of each profile. The links
+// are relative to the index page so the pages can be moved together.
+func buildIndex(conf config, profiles []config) []indexEntry {
+	dir := filepath.Dir(conf.IndexOut)
+	var entries []indexEntry
+	for _, p := range profiles {
+		entry := indexEntry{Name: p.Name, Title: p.HTMLTitle}
+		for _, out := range []indexLink{
+			{"Collection", p.HTMLOut},
+			{"Dashboard", p.DashboardOut},
+			{"Needs", p.NeedsOut},
+			{"Stacks", p.StackReportOut},
+			{"Shopping list", p.ShoppingListOut},
+			{"Changes", p.ChangesOut},
+			{"JSON", p.JSONOut},
+			{"CSV", p.CSVOut},
+		} {
+			if out.Href == "" {
+				continue
+			}
+			if rel, err := filepath.Rel(dir, out.Href); err == nil {
+				out.Href = rel
+			}
+			out.Href = filepath.ToSlash(out.Href)
+			entry.Links = append(entry.Links, out)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// indexTemplateDef is the html/template definition for the index page. All
+// data is escaped for HTML.
+const indexTemplateDef = `<!DOCTYPE html>
+<html>
+    <head><title>{{.Title}}</title></head>
+    <body>
+	    <h1>{{.Title}}</h1>
+		<ul>
+		{{range .Entries}}<li>{{.Name}}{{if .Title}} - {{.Title}}{{end}}
+			{{range .Links}}- <a href="{{.Href}}">{{.Label}}</a> {{end}}</li>
+		{{end}}
+		</ul>
+	</body>
+</html>
+`
+
+// writeIndexHTML will output the index page linking to the pages of each
+// profile.
+func writeIndexHTML(w *bufio.Writer, conf config, profiles []config) error {
+	indexTemplate, err := template.New("index").Parse(indexTemplateDef)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return indexTemplate.Execute(w, struct {
+		Title   string
+		Entries []indexEntry
+	}{conf.HTMLTitle, buildIndex(conf, profiles)})
+}
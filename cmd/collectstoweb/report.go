@@ -0,0 +1,308 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/nuttann/equtils/pkg/eqdb"
+)
+
+// The report structures hold the merged quest data and house contents. The
+// whole report is built before any output is written, and each output format
+// (HTML, JSON, and CSV) is produced from the same report. The JSON output is
+// a direct encoding of these structures.
+
+// collectionReport is the top-level report for all configured houses.
+type collectionReport struct {
+	Title     string        `json:"title"`
+	Houses    []houseReport `json:"houses"`
+	Dashboard dashboard     `json:"dashboard"` // Totals across all houses
+	Stacks    []stackReport `json:"stackreport"`
+	Changes   *changeReport `json:"changes,omitempty"` // Changes since the previous snapshot
+	Needs     []toonNeeds   `json:"needs,omitempty"`   // Stored items needed by characters
+
+	snapshot eqdb.StockSnapshot // Stored counts to save in the history
+}
+
+// houseReport holds the configured expansions for a house along with any
+// stored items that do not belong to the configured quests.
+type houseReport struct {
+	Address    string       `json:"address"`
+	Fname      string       `json:"file"`
+	Have       int          `json:"have"`  // Unique quest items present
+	Total      int          `json:"total"` // Unique quest items configured
+	Expansions []expReport  `json:"expansions"`
+	Extras     []itemReport `json:"extras,omitempty"` // Stored items not in any quest
+}
+
+// expReport holds the zones of an expansion that are configured for a house.
+type expReport struct {
+	Name  string       `json:"name"`
+	Have  int          `json:"have"`
+	Total int          `json:"total"`
+	Zones []zoneReport `json:"zones"`
+}
+
+// zoneReport holds the quests for a zone.
+type zoneReport struct {
+	Name   string        `json:"name"`
+	Have   int           `json:"have"`
+	Total  int           `json:"total"`
+	Quests []questReport `json:"quests"`
+}
+
+// questReport holds all items of a quest and the house counts for them.
+type questReport struct {
+	Name  string       `json:"name"`
+	Note  string       `json:"note,omitempty"`
+	Have  int          `json:"have"`
+	Total int          `json:"total"`
+	Items []itemReport `json:"items"`
+}
+
+// itemReport holds the house count for a single item. Name will be "???" if
+// the name is not known.
+type itemReport struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Count    int    `json:"count"` // Less any reserved or handed out
+	Stacks   int    `json:"stacks"`
+	Reserved int    `json:"reserved,omitempty"` // Pending reservations
+}
+
+// buildReport will merge the quest data with the contents of all configured
+// houses. The dashboard is built from the contents of all houses. Nothing is
+// saved, so the stock history is only updated by saveHistory.
+//
+// Error reasons:
+//   - A real-estate file cannot be read.
+//   - A quest has a bad item ID range.
+func buildReport(state intState, conf config) (collectionReport, error) {
+	report := collectionReport{Title: conf.HTMLTitle}
+	stock, err := loadStock(state, conf)
+	if err != nil {
+		return report, err
+	}
+	report.snapshot = stockSnapshot(stock)
+	report.Changes = buildChanges(state, report.snapshot)
+	for _, house := range conf.Houses {
+		for _, hs := range stock {
+			if hs.Address == house.Address {
+				hr, err := buildHouse(state, house, hs.Items)
+				if err != nil {
+					return report, err
+				}
+				report.Houses = append(report.Houses, hr)
+				break
+			}
+		}
+	}
+	if report.Dashboard, err = buildDashboard(state, stock); err != nil {
+		return report, err
+	}
+	if report.Needs, err = buildNeeds(state, stock); err != nil {
+		return report, err
+	}
+	report.Stacks = buildStackReports(conf, stock)
+	printStackReports(report.Stacks)
+	if report.Changes != nil && len(report.Changes.Houses) > 0 {
+		w := bufio.NewWriter(os.Stdout)
+		writeChanges(w, report)
+		fmt.Fprintln(w)
+		w.Flush()
+	}
+	return report, nil
+}
+
+// houseStock holds the stored items for a single house.
+type houseStock struct {
+	Address string
+	Fname   string // Real-estate file for the house
+	Items   itemMap
+}
+
+// loadStock will return the stored items for every configured house. The same
+// house may be configured more than once, but is only listed once.
+func loadStock(state intState, conf config) ([]houseStock, error) {
+	var stock []houseStock
+	seen := make(map[string]bool)
+	for _, house := range conf.Houses {
+		if !seen[house.Address] {
+			seen[house.Address] = true
+			items, err := getStoredItemData(state, house)
+			if err != nil {
+				return nil, err
+			}
+			stock = append(stock, houseStock{
+				Address: house.Address,
+				Fname:   house.Fname,
+				Items:   items,
+			})
+		}
+	}
+	applyLedger(state, stock)
+	return stock, nil
+}
+
+// applyLedger will subtract pending reservations and recent hand-outs in the
+// ledger from the house counts. Hand-outs are only subtracted if they were
+// made after the real-estate file was written, since they will not be in later
+// dumps. Entries without an address are taken from the first house holding
+// the item.
+func applyLedger(state intState, stock []houseStock) {
+	if state.Ledger == nil {
+		return
+	}
+	dumpTimes := make(map[string]time.Time)
+	for _, hs := range stock {
+		if info, err := os.Stat(hs.Fname); err == nil {
+			dumpTimes[hs.Fname] = info.ModTime()
+		}
+	}
+	for _, entry := range state.Ledger.Entries {
+		var hs *houseStock
+		for n := range stock {
+			if entry.Address != "" {
+				if stock[n].Address == entry.Address {
+					hs = &stock[n]
+					break
+				}
+			} else if stock[n].Items[entry.ID].Count > 0 {
+				hs = &stock[n]
+				break
+			}
+		}
+		if hs == nil {
+			continue // Not in any configured house.
+		}
+		item, ok := hs.Items[entry.ID]
+		if !ok {
+			continue
+		}
+		if entry.Pending() {
+			item.Reserved += entry.Count
+		} else if !entry.HandedOut.After(dumpTimes[hs.Fname]) {
+			continue // Already reflected in the dump.
+		}
+		item.Count -= entry.Count
+		if item.Count < 0 {
+			item.Count = 0
+		}
+		hs.Items[entry.ID] = item
+	}
+}
+
+// buildHouse will create the report for a single house from its stored items.
+// Any stored items that were not used by the configured quests are added as
+// extras and listed on the terminal.
+func buildHouse(state intState, house House, items itemMap) (houseReport, error) {
+	hr := houseReport{Address: house.Address, Fname: house.Fname}
+	used := make(map[int]bool)
+	for _, houseExp := range house.Expansions {
+		for _, questExp := range *state.QuestData {
+			if houseExp.Name == questExp.Name { // Found the quest data for this expansion.
+				er := expReport{Name: questExp.Name}
+				if houseExp.Zones == nil {
+					// Do all zones in this expansion.
+					for _, questZone := range questExp.Zones {
+						zr, err := buildZone(state, house, questZone, items, used)
+						if err != nil {
+							return hr, err
+						}
+						er.Zones = append(er.Zones, zr)
+					}
+				} else {
+					// Do specific zones listed in order listed.
+					for _, loczone := range houseExp.Zones {
+						// Find the zone in quests.
+						for _, questZone := range questExp.Zones {
+							if loczone == questZone.Name {
+								zr, err := buildZone(state, house, questZone, items, used)
+								if err != nil {
+									return hr, err
+								}
+								er.Zones = append(er.Zones, zr)
+								break // Handled matching zone.
+							}
+						}
+					}
+				}
+				for _, zr := range er.Zones {
+					er.Have += zr.Have
+					er.Total += zr.Total
+				}
+				hr.Expansions = append(hr.Expansions, er)
+				hr.Have += er.Have
+				hr.Total += er.Total
+				break // Handled matching expansion.
+			}
+		}
+	}
+	for id, item := range items {
+		if !used[id] {
+			hr.Extras = append(hr.Extras, itemReport{
+				ID:       id,
+				Name:     item.Name,
+				Count:    item.Count,
+				Stacks:   item.Stacks,
+				Reserved: item.Reserved,
+			})
+		}
+	}
+	sort.Slice(hr.Extras, func(i, j int) bool {
+		return hr.Extras[i].Name < hr.Extras[j].Name
+	})
+	if len(hr.Extras) > 0 {
+		fmt.Println("====== Extra items in -", house.Address)
+		for _, item := range hr.Extras {
+			fmt.Println(item.Name, " (", item.Count, ")")
+		}
+		fmt.Println()
+	}
+	return hr, nil
+}
+
+// buildZone will create the report for all collection quests / items for a
+// zone. IDs of items found in the house are added to 'used'.
+func buildZone(state intState, house House, zone eqdb.QuestZone, items itemMap, used map[int]bool) (zoneReport, error) {
+	zr := zoneReport{Name: zone.Name}
+	for _, quest := range zone.Quests {
+		ids, err := quest.IDs()
+		if err != nil {
+			return zr, fmt.Errorf("Quest file bad int range - %v", err)
+		}
+		qr := questReport{Name: quest.Name, Note: quest.Note, Total: len(ids)}
+		for _, id := range ids {
+			name := state.ItemDB.Name(id)
+			if name == "" {
+				name = "???" // Use this if name is not known.
+			}
+			if _, ok := items[id]; ok {
+				used[id] = true // Stored, even if all are reserved.
+			}
+			count := items[id].Count
+			if count > 0 {
+				qr.Have++ // Keep count of unique items for this quest in the house.
+			}
+			qr.Items = append(qr.Items, itemReport{
+				ID:       id,
+				Name:     name,
+				Count:    count,
+				Stacks:   items[id].Stacks,
+				Reserved: items[id].Reserved,
+			})
+		}
+		zr.Quests = append(zr.Quests, qr)
+		// Add counts to totals for zone.
+		zr.Total += qr.Total
+		zr.Have += qr.Have
+	}
+	return zr, nil
+}
@@ -0,0 +1,148 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// mailBatchSize is the maximum number of items to request in a single mail.
+const mailBatchSize = 10
+
+// wishItem is a single line from a wish list after being resolved through the
+// item DB and the house contents.
+type wishItem struct {
+	Line    int    // Line number in the wish list
+	Text    string // Text as given in the wish list
+	ID      int    // Item ID (0 if not resolved)
+	Name    string // Item name
+	Address string // House holding the item ("" if out of stock)
+	Problem string // Why the item cannot be requested ("" if it can)
+}
+
+// readWishList will read a wish list file. Each line holds an item name or an
+// item ID. Blank lines and lines starting with "#" are ignored. The line
+// numbers are returned along with the text.
+func readWishList(fname string) (lines []int, texts []string, err error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		lines = append(lines, lineNo)
+		texts = append(texts, text)
+	}
+	return lines, texts, scanner.Err()
+}
+
+// resolveWish will find the item for the wish list text and the first house
+// holding it. Names must match an item DB name (ignoring case) or be part of
+// exactly one item DB name.
+func resolveWish(state intState, stock []houseStock, lineNo int, text string) wishItem {
+	wish := wishItem{Line: lineNo, Text: text}
+	if id, err := strconv.Atoi(text); err == nil {
+		wish.ID = id
+	} else {
+		ids := state.ItemDB.FindName(text)
+		if len(ids) == 0 {
+			ids = state.ItemDB.Search(text)
+		}
+		switch len(ids) {
+		case 0:
+			wish.Problem = "unknown item"
+			return wish
+		case 1:
+			wish.ID = ids[0]
+		default:
+			var names []string
+			for _, id := range ids {
+				names = append(names, fmt.Sprintf("%s (%d)", state.ItemDB.Name(id), id))
+			}
+			wish.Problem = "matches several items - " + strings.Join(names, ", ")
+			return wish
+		}
+	}
+	wish.Name = state.ItemDB.Name(wish.ID)
+	for _, hs := range stock {
+		if item, ok := hs.Items[wish.ID]; ok && item.Count > 0 {
+			wish.Address = hs.Address
+			wish.Name = item.Name
+			return wish
+		}
+	}
+	if wish.Name == "" {
+		wish.Name = "???" // Use this if name is not known.
+	}
+	wish.Problem = "out of stock"
+	return wish
+}
+
+// writeRequestMail will resolve the items in the wish list file and output the
+// text of the mails to request them. There is a mail per house for each batch
+// of up to 10 items. Each mail starts with the house address followed by an
+// item per line. Items that cannot be requested are listed at the end.
+func writeRequestMail(w *bufio.Writer, state intState, conf config, wishFile string) error {
+	lines, texts, err := readWishList(wishFile)
+	if err != nil {
+		return err
+	}
+	stock, err := loadStock(state, conf)
+	if err != nil {
+		return err
+	}
+	var problems []wishItem
+	var addresses []string // Houses in order of first use
+	byHouse := make(map[string][]wishItem)
+	for i, text := range texts {
+		wish := resolveWish(state, stock, lines[i], text)
+		if wish.Problem != "" {
+			problems = append(problems, wish)
+			continue
+		}
+		if _, ok := byHouse[wish.Address]; !ok {
+			addresses = append(addresses, wish.Address)
+		}
+		byHouse[wish.Address] = append(byHouse[wish.Address], wish)
+	}
+	for _, address := range addresses {
+		wishes := byHouse[address]
+		batches := (len(wishes) + mailBatchSize - 1) / mailBatchSize
+		for batch := 0; batch < batches; batch++ {
+			end := (batch + 1) * mailBatchSize
+			if end > len(wishes) {
+				end = len(wishes)
+			}
+			w.WriteString(fmt.Sprintf("==== Mail %d of %d for %s\n", batch+1, batches, address))
+			w.WriteString(address + "\n")
+			for _, wish := range wishes[batch*mailBatchSize : end] {
+				w.WriteString(wish.Name + "\n")
+			}
+			w.WriteString("\n")
+		}
+	}
+	if len(problems) > 0 {
+		w.WriteString("==== Items that cannot be requested\n")
+		for _, wish := range problems {
+			text := wish.Text
+			if wish.Name != "" && wish.Name != wish.Text {
+				text += " (" + wish.Name + ")"
+			}
+			w.WriteString(fmt.Sprintf("%s: line %d: %s - %s\n", wishFile, wish.Line, text, wish.Problem))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/nuttann/equtils/pkg/eqfile"
+)
+
+// server holds the latest report for serving. The report is rebuilt when any
+// of the house files change. The mutex protects the report and the state
+// (including the item DB) while being rebuilt.
+type server struct {
+	mu     sync.RWMutex
+	state  intState
+	conf   config
+	report collectionReport
+}
+
+// searchResult is a single item returned by the item search.
+type searchResult struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	IconID int    `json:"iconid"`
+}
+
+// serve will serve the collection pages and reports over HTTP on the address
+// (E.g., ":8080"). The house files are checked for changes at every interval
+// and the report is rebuilt when they change. This never returns.
+//
+// Pages:
+//   - "/" - Collection page (Same as htmlout)
+//   - "/dashboard" - Dashboard page (Same as dashboardout)
+//   - "/stacks" - Stack report page (Same as stackreportout)
+//   - "/shopping.txt" - Shopping list (Same as shoppinglistout)
+//   - "/changes.txt" - Stock changes (Same as changesout)
+//   - "/needs" - Stored items needed by characters (Same as needsout)
+//   - "/history?item=ITEM" - Stock history of an item (name or ID)
+//   - "/report.json" - JSON report (Same as jsonout)
+//   - "/report.csv" - CSV report (Same as csvout)
+//   - "/items?q=TEXT" - JSON list of items whose names contain TEXT
+func serve(state intState, conf config, addr string, interval time.Duration) {
+	report, err := buildReport(state, conf)
+	if err != nil {
+		log.Fatalf("error: %v", err)
+	}
+	if err = saveHistory(state, report); err != nil {
+		log.Fatalf("error: Saving stock history - %v", err)
+	}
+	s := &server{
+		state:  state,
+		conf:   conf,
+		report: report,
+	}
+	state.ItemDB.Close() // Save any new item data.
+	go s.refresh(interval)
+
+	mainPage := s.handlePage(func(w *bufio.Writer, report collectionReport) error {
+		return writeHTML(w, s.conf, report)
+	}, "text/html; charset=utf-8")
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r) // "/" matches all paths not handled elsewhere.
+			return
+		}
+		mainPage(w, r)
+	})
+	http.HandleFunc("/dashboard", s.handlePage(writeDashboardHTML, "text/html; charset=utf-8"))
+	http.HandleFunc("/stacks", s.handlePage(writeStacksHTML, "text/html; charset=utf-8"))
+	http.HandleFunc("/shopping.txt", s.handlePage(writeShoppingList, "text/plain; charset=utf-8"))
+	http.HandleFunc("/changes.txt", s.handlePage(writeChanges, "text/plain; charset=utf-8"))
+	http.HandleFunc("/history", s.handleHistory)
+	http.HandleFunc("/needs", s.handlePage(writeNeedsHTML, "text/html; charset=utf-8"))
+	http.HandleFunc("/report.json", s.handlePage(writeJSON, "application/json"))
+	http.HandleFunc("/report.csv", s.handlePage(writeCSV, "text/csv"))
+	http.HandleFunc("/items", s.handleItems)
+	fmt.Println("Serving on", addr)
+	log.Fatal(http.ListenAndServe(addr, nil))
+}
+
+// refresh will rebuild the report whenever any of the house files or the
+// ledger change. If the report cannot be built (E.g., EQ is still writing a
+// house file), the error is printed and the previous report is served.
+func (s *server) refresh(interval time.Duration) {
+	watcher := eqfile.NewWatcher(watchedFiles(s.conf))
+	for {
+		time.Sleep(interval)
+		changed := watcher.Changed()
+		if len(changed) == 0 {
+			continue
+		}
+		for _, fname := range changed {
+			fmt.Println("Changed -", fname)
+		}
+		s.mu.Lock()
+		reloadChanged(&s.state, s.conf, changed)
+		if report, err := buildReport(s.state, s.conf); err == nil {
+			s.report = report
+			if err = saveHistory(s.state, report); err != nil {
+				fmt.Printf("error: Saving stock history - %v\n", err)
+			}
+		} else {
+			fmt.Printf("error: %v - keeping the previous report\n", err)
+		}
+		s.state.ItemDB.Close() // Updates if anything was changed.
+		s.mu.Unlock()
+	}
+}
+
+// handlePage will return a handler that writes the latest report using the
+// 'write' function.
+func (s *server) handlePage(write func(*bufio.Writer, collectionReport) error, contentType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		w.Header().Set("Content-Type", contentType)
+		bw := bufio.NewWriter(w)
+		err := write(bw, s.report)
+		if err == nil {
+			err = bw.Flush()
+		}
+		if err != nil {
+			fmt.Println("error: Writing page -", err)
+		}
+	}
+}
+
+// handleItems will search the item DB for names containing the "q" parameter
+// and return the matching items as JSON.
+func (s *server) handleItems(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		http.Error(w, "missing q parameter", http.StatusBadRequest)
+		return
+	}
+	s.mu.RLock()
+	results := []searchResult{}
+	for _, id := range s.state.ItemDB.Search(query) {
+		item := s.state.ItemDB.GetItem(id)
+		results = append(results, searchResult{
+			ID:     id,
+			Name:   item.Name,
+			IconID: item.IconID,
+		})
+	}
+	s.mu.RUnlock()
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(results)
+	if err != nil {
+		fmt.Println("error: Writing items -", err)
+	}
+}
+
+// handleHistory will return the stock history page for the item given by the
+// "item" parameter.
+func (s *server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	id, err := findItem(s.state, r.URL.Query().Get("item"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	bw := bufio.NewWriter(w)
+	err = writeItemHistoryHTML(bw, buildItemHistory(s.state, id))
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err != nil {
+		fmt.Println("error: Writing page -", err)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"html/template"
+	"log"
+	"sort"
+)
+
+// The stack report helps keep one stack per item in each house. A house can
+// only store a limited number of stacks, so items split across stacks waste
+// slots. Items with more than the keep threshold are surplus that can be given
+// away.
+
+// stackReport holds the stack hygiene for a house. All stored items are
+// included, not only the configured quest items.
+type stackReport struct {
+	Address     string      `json:"address"`
+	Slots       int         `json:"slots,omitempty"` // Storage slots (0 if not configured)
+	UsedSlots   int         `json:"usedslots"`       // Stacks stored
+	FreeSlots   *int        `json:"freeslots"`       // nil if slots not configured
+	WastedSlots int         `json:"wastedslots"`     // Extra stacks of split items
+	Split       []stackItem `json:"split"`           // Items in more than one stack
+	Surplus     []stackItem `json:"surplus"`         // Items over the keep threshold
+}
+
+// stackItem is an item listed in a stack report.
+type stackItem struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Count   int    `json:"count"`
+	Stacks  int    `json:"stacks"`
+	Surplus int    `json:"surplus,omitempty"` // Count over the keep threshold
+}
+
+// houseSlots will return the storage slots configured for the house. A house
+// setting overrides the overall setting.
+func houseSlots(conf config, address string) int {
+	for _, house := range conf.Houses {
+		if house.Address == address && house.Slots > 0 {
+			return house.Slots
+		}
+	}
+	return conf.HouseSlots
+}
+
+// buildStackReports will create a stack report for each house. The counts are
+// those in the real-estate file, since the stacks are for the items in it.
+// Reserved items are still in the house, so they are included.
+func buildStackReports(conf config, stock []houseStock) []stackReport {
+	var reports []stackReport
+	for _, hs := range stock {
+		sr := stackReport{Address: hs.Address, Slots: houseSlots(conf, hs.Address)}
+		for id, item := range hs.Items {
+			count := item.Stored // Count before the ledger is applied
+			sr.UsedSlots += item.Stacks
+			si := stackItem{ID: id, Name: item.Name, Count: count, Stacks: item.Stacks}
+			if item.Stacks > 1 {
+				sr.WastedSlots += item.Stacks - 1
+				sr.Split = append(sr.Split, si)
+			}
+			if conf.KeepThreshold > 0 && count > conf.KeepThreshold {
+				si.Surplus = count - conf.KeepThreshold
+				sr.Surplus = append(sr.Surplus, si)
+			}
+		}
+		if sr.Slots > 0 {
+			free := sr.Slots - sr.UsedSlots
+			sr.FreeSlots = &free
+		}
+		sortStackItems(sr.Split)
+		sortStackItems(sr.Surplus)
+		reports = append(reports, sr)
+	}
+	return reports
+}
+
+// sortStackItems will sort the items by name and then by ID.
+func sortStackItems(items []stackItem) {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Name != items[j].Name {
+			return items[i].Name < items[j].Name
+		}
+		return items[i].ID < items[j].ID
+	})
+}
+
+// printStackReports will list the stack reports on the terminal. Houses with
+// nothing to report are skipped.
+func printStackReports(reports []stackReport) {
+	for _, sr := range reports {
+		if len(sr.Split) == 0 && len(sr.Surplus) == 0 && sr.FreeSlots == nil {
+			continue
+		}
+		fmt.Println("====== Stacks in -", sr.Address)
+		for _, item := range sr.Split {
+			fmt.Printf("Multiple stacks - %s (%d in %d stacks)\n", item.Name, item.Count, item.Stacks)
+		}
+		for _, item := range sr.Surplus {
+			fmt.Printf("Surplus - %s (%d, %d over keep threshold)\n", item.Name, item.Count, item.Surplus)
+		}
+		if sr.FreeSlots != nil {
+			fmt.Printf("Slots - %d used of %d (%d free, %d wasted by split stacks)\n",
+				sr.UsedSlots, sr.Slots, *sr.FreeSlots, sr.WastedSlots)
+		}
+		fmt.Println()
+	}
+}
+
+// stackTemplateDef is the html/template definition for the stack report page.
+// All data is escaped for HTML.
+const stackTemplateDef = `<!DOCTYPE html>
+<html>
+    <head><title>{{.Title}} - Stacks</title></head>
+    <body>
+	    <h1>{{.Title}} - Stacks</h1>
+		{{range .Stacks}}
+		<h2>{{.Address}}</h2>
+		<p>Slots used = {{.UsedSlots}}{{if .FreeSlots}} / {{.Slots}} (Free = {{.FreeSlots}}){{end}}
+			- Wasted by split stacks = {{.WastedSlots}}</p>
+		{{if .Split}}
+		<h3>Items in more than one stack</h3>
+		<ul>
+		{{range .Split}}<li>{{.Name}} ({{.Count}} in {{.Stacks}} stacks)</li>
+		{{end}}
+		</ul>
+		{{end}}
+		{{if .Surplus}}
+		<h3>Surplus items</h3>
+		<ul>
+		{{range .Surplus}}<li>{{.Name}} ({{.Count}}, {{.Surplus}} over keep threshold)</li>
+		{{end}}
+		</ul>
+		{{end}}
+		{{end}}
+	</body>
+</html>
+`
+
+// writeStacksHTML will output the stack reports as an HTML page.
+func writeStacksHTML(w *bufio.Writer, report collectionReport) error {
+	stackTemplate, err := template.New("stacks").Parse(stackTemplateDef)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return stackTemplate.Execute(w, report)
+}
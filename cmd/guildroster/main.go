@@ -0,0 +1,152 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nuttann/equtils/pkg/eqdb"
+	"github.com/nuttann/equtils/pkg/eqfile"
+	"gopkg.in/yaml.v2"
+)
+
+// 'config' holds the locations of the data files.
+type config struct {
+	RosterLoc    string   // Roster snapshots location (currently file name)
+	Rosters      []string // Guild roster files to add as snapshots
+	InactiveDays int      // Days since last on to be inactive
+}
+
+// defaultInactiveDays is used if no inactive days are configured.
+const defaultInactiveDays = 30
+
+func readConfig(confFile string) (confData config) {
+	// Read configuration data.
+	buf, err := ioutil.ReadFile(confFile)
+	if err != nil {
+		log.Fatalf("error: Configuration file - %v", err)
+	}
+	err = yaml.Unmarshal(buf, &confData)
+	if err != nil {
+		log.Fatalf("error: Configuration file - %v", err)
+	}
+	if confData.RosterLoc == "" {
+		log.Fatalf("error: Configuration file - no rosterloc given")
+	}
+	if confData.InactiveDays == 0 {
+		confData.InactiveDays = defaultInactiveDays
+	}
+	return
+}
+
+// readSnapshot will return the members in the guild roster file as a
+// snapshot taken when the file was last modified.
+func readSnapshot(fname string) (eqdb.RosterSnapshot, error) {
+	info, err := os.Stat(fname)
+	if err != nil {
+		return eqdb.RosterSnapshot{}, err
+	}
+	members, err := eqfile.ReadGuild(fname)
+	if err != nil {
+		return eqdb.RosterSnapshot{}, err
+	}
+	snap := eqdb.RosterSnapshot{Time: info.ModTime()}
+	for _, m := range members {
+		snap.Members = append(snap.Members, eqdb.RosterMember{
+			Name:   m.Name,
+			Level:  m.Level,
+			Class:  m.Class,
+			Rank:   m.Rank,
+			Alt:    m.Alt,
+			LastOn: m.LastOn,
+			Zone:   m.Zone,
+			Notes:  m.Notes,
+		})
+	}
+	return snap, nil
+}
+
+// reports are the names of the reports in the order written.
+var reports = []string{"changes", "inactive", "alts", "classes"}
+
+func main() {
+	confPtr := flag.String("conf", "", "Configuration file. (Required)")
+	reportPtr := flag.String("report", "all", "Report to write: "+strings.Join(reports, ", ")+", or all.")
+	daysPtr := flag.Int("days", 0, "Days since last on to be inactive. (Overrides inactivedays)")
+	mainsPtr := flag.Bool("mains", false, "Leave alts out of the class and level distribution.")
+	flag.Parse()
+
+	if *confPtr == "" {
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+	if *reportPtr != "all" && !contains(reports, *reportPtr) {
+		log.Fatalf("error: -report must be one of %s, or all", strings.Join(reports, ", "))
+	}
+	conf := readConfig(*confPtr)
+	if *daysPtr > 0 {
+		conf.InactiveDays = *daysPtr
+	}
+
+	roster, err := eqdb.OpenRoster(conf.RosterLoc)
+	if err != nil {
+		log.Fatalf("error: Roster file - %v", err)
+	}
+	failed := false
+	for _, fname := range conf.Rosters {
+		snap, err := readSnapshot(fname)
+		if err != nil {
+			fmt.Printf("error: %v\n", err) // Keep going with the other files.
+			failed = true
+			continue
+		}
+		if roster.Add(snap) {
+			fmt.Printf("Added snapshot of %d members from %s\n", len(snap.Members), fname)
+		}
+	}
+	if err = roster.Close(); err != nil {
+		log.Fatalf("error: Roster file - %v", err)
+	}
+
+	latest, ok := roster.Latest()
+	if !ok {
+		log.Fatalf("error: No roster snapshots - add a guild roster file to rosters")
+	}
+	fmt.Printf("Roster of %s - %d members\n", latest.Time.Format("2006-01-02 15:04"), len(latest.Members))
+	for _, name := range reports {
+		if *reportPtr != "all" && *reportPtr != name {
+			continue
+		}
+		switch name {
+		case "changes":
+			writeChanges(os.Stdout, &roster)
+		case "inactive":
+			writeInactive(os.Stdout, latest, time.Duration(conf.InactiveDays)*24*time.Hour)
+		case "alts":
+			writeAlts(os.Stdout, latest)
+		case "classes":
+			writeClasses(os.Stdout, latest, *mainsPtr)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// contains returns true if the string is in the list.
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
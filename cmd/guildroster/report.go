@@ -0,0 +1,179 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/nuttann/equtils/pkg/eqdb"
+)
+
+// describe will return the member as "NAME (LEVEL CLASS)".
+func describe(m eqdb.RosterMember) string {
+	return fmt.Sprintf("%s (%d %s)", m.Name, m.Level, m.Class)
+}
+
+// writeChanges will output the members that joined or left between the last
+// two snapshots.
+func writeChanges(w io.Writer, roster *eqdb.Roster) {
+	n := len(roster.Snapshots)
+	if n < 2 {
+		fmt.Fprintln(w, "==== Changes - only one snapshot")
+		return
+	}
+	before, after := roster.Snapshots[n-2], roster.Snapshots[n-1]
+	changes := eqdb.DiffRoster(before, after)
+	fmt.Fprintf(w, "==== Changes since %s - %d joined, %d left\n",
+		before.Time.Format("2006-01-02 15:04"), len(changes.Joined), len(changes.Left))
+	for _, m := range changes.Joined {
+		fmt.Fprintf(w, "    Joined - %s\n", describe(m))
+	}
+	for _, m := range changes.Left {
+		fmt.Fprintf(w, "    Left - %s\n", describe(m))
+	}
+}
+
+// writeInactive will output the members not on for the given time before the
+// snapshot, longest first. Members never on are listed first.
+func writeInactive(w io.Writer, snap eqdb.RosterSnapshot, limit time.Duration) {
+	var inactive []eqdb.RosterMember
+	for _, m := range snap.Members {
+		if snap.Time.Sub(m.LastOn) >= limit {
+			inactive = append(inactive, m)
+		}
+	}
+	sort.SliceStable(inactive, func(i, j int) bool { return inactive[i].LastOn.Before(inactive[j].LastOn) })
+	fmt.Fprintf(w, "==== Inactive for %d days - %d members\n", int(limit.Hours()/24), len(inactive))
+	for _, m := range inactive {
+		last := "never"
+		if !m.LastOn.IsZero() {
+			last = fmt.Sprintf("%s (%d days)", m.LastOn.Format("2006-01-02"), int(snap.Time.Sub(m.LastOn).Hours()/24))
+		}
+		alt := ""
+		if m.Alt {
+			alt = " - alt"
+		}
+		fmt.Fprintf(w, "    %s - %s - last on %s%s\n", describe(m), m.Rank, last, alt)
+	}
+}
+
+// findMain will return the main of the alt. The main is a member not flagged
+// as an alt whose name is a word in the notes of the alt (E.g., "Nuttann's
+// alt" or "alt of Nuttann"). It returns false if no main is found.
+func findMain(snap eqdb.RosterSnapshot, alt eqdb.RosterMember) (eqdb.RosterMember, bool) {
+	words := strings.FieldsFunc(alt.Notes, func(r rune) bool { return !unicode.IsLetter(r) })
+	for _, word := range words {
+		if m, ok := snap.Member(word); ok && !m.Alt {
+			return m, true
+		}
+	}
+	return eqdb.RosterMember{}, false
+}
+
+// writeAlts will output each main with its alts followed by the alts whose
+// main could not be found from their notes.
+func writeAlts(w io.Writer, snap eqdb.RosterSnapshot) {
+	alts := make(map[string][]eqdb.RosterMember)
+	var mains []eqdb.RosterMember
+	var unknown []eqdb.RosterMember
+	count := 0
+	for _, m := range snap.Members {
+		if !m.Alt {
+			continue
+		}
+		count++
+		main, ok := findMain(snap, m)
+		if !ok {
+			unknown = append(unknown, m)
+			continue
+		}
+		if alts[main.Name] == nil {
+			mains = append(mains, main)
+		}
+		alts[main.Name] = append(alts[main.Name], m)
+	}
+	sort.Slice(mains, func(i, j int) bool { return mains[i].Name < mains[j].Name })
+	fmt.Fprintf(w, "==== Alts - %d alts of %d mains\n", count, len(mains))
+	for _, main := range mains {
+		var list []string
+		for _, alt := range alts[main.Name] {
+			list = append(list, describe(alt))
+		}
+		fmt.Fprintf(w, "    %s - %s\n", describe(main), strings.Join(list, ", "))
+	}
+	for _, alt := range unknown {
+		fmt.Fprintf(w, "    Main not found - %s - notes \"%s\"\n", describe(alt), alt.Notes)
+	}
+}
+
+// classCount holds the distribution for a class.
+type classCount struct {
+	Class string
+	Count int
+	Top   int // Members at the top level of the roster
+	Total int // Sum of levels
+}
+
+// writeClasses will output the count of members of each class, most first,
+// and the count of members in each range of ten levels, highest first.
+func writeClasses(w io.Writer, snap eqdb.RosterSnapshot, mainsOnly bool) {
+	var members []eqdb.RosterMember
+	top := 0
+	for _, m := range snap.Members {
+		if mainsOnly && m.Alt {
+			continue
+		}
+		members = append(members, m)
+		if m.Level > top {
+			top = m.Level
+		}
+	}
+	byClass := make(map[string]*classCount)
+	var classes []*classCount
+	levels := make(map[int]int) // Counts by range (E.g., 11 for 111-120)
+	for _, m := range members {
+		c, ok := byClass[m.Class]
+		if !ok {
+			c = &classCount{Class: m.Class}
+			byClass[m.Class] = c
+			classes = append(classes, c)
+		}
+		c.Count++
+		c.Total += m.Level
+		if m.Level == top {
+			c.Top++
+		}
+		levels[(m.Level-1)/10]++
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		if classes[i].Count != classes[j].Count {
+			return classes[i].Count > classes[j].Count
+		}
+		return classes[i].Class < classes[j].Class
+	})
+	who := "members"
+	if mainsOnly {
+		who = "mains"
+	}
+	fmt.Fprintf(w, "==== Classes - %d %s\n", len(members), who)
+	for _, c := range classes {
+		fmt.Fprintf(w, "    %-14s %3d - %d at level %d - average level %d\n",
+			c.Class, c.Count, c.Top, top, c.Total/c.Count)
+	}
+	var ranges []int
+	for r := range levels {
+		ranges = append(ranges, r)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(ranges)))
+	fmt.Fprintf(w, "==== Levels - %d %s\n", len(members), who)
+	for _, r := range ranges {
+		fmt.Fprintf(w, "    %3d-%-3d %3d\n", r*10+1, r*10+10, levels[r])
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nuttann/equtils/pkg/eqdb"
+	"gopkg.in/yaml.v2"
+)
+
+// 'config' holds the locations of the item DB and ledger. This uses the same
+// configuration file as the "collectstoweb" command, so other fields in the
+// file are ignored.
+type config struct {
+	ItemDBLoc string // DB location info (currently file name)
+	LedgerLoc string // Reservation ledger location
+}
+
+func readConfig(confFile string) (confData config) {
+	// Read configuration data.
+	buf, err := ioutil.ReadFile(confFile)
+	if err != nil {
+		log.Fatalf("error: Configuration file - %v", err)
+	}
+	err = yaml.Unmarshal(buf, &confData)
+	if err != nil {
+		log.Fatalf("error: Configuration file - %v", err)
+	}
+	if confData.LedgerLoc == "" {
+		log.Fatalf("error: Configuration file - no ledgerloc given")
+	}
+	return
+}
+
+// resolveItem will return the item ID for an item name or ID. The name must
+// match exactly one item in the item DB (ignoring case).
+func resolveItem(itemDB *eqdb.Items, text string) (int, error) {
+	if id, err := strconv.Atoi(text); err == nil {
+		return id, nil
+	}
+	ids := itemDB.FindName(text)
+	switch len(ids) {
+	case 0:
+		return 0, fmt.Errorf("no item named \"%s\" in the item DB", text)
+	case 1:
+		return ids[0], nil
+	}
+	var names []string
+	for _, id := range ids {
+		names = append(names, strconv.Itoa(id))
+	}
+	return 0, fmt.Errorf("several items named \"%s\" - use an ID (%s)",
+		text, strings.Join(names, ", "))
+}
+
+// listPending will print the pending reservations grouped by recipient.
+func listPending(ledger *eqdb.Ledger, itemDB *eqdb.Items) {
+	pending := ledger.Pending()
+	if len(pending) == 0 {
+		fmt.Println("No outstanding reservations.")
+		return
+	}
+	last := ""
+	for _, entry := range pending {
+		if !strings.EqualFold(entry.Recipient, last) {
+			fmt.Println("====", entry.Recipient)
+			last = entry.Recipient
+		}
+		name := itemDB.Name(entry.ID)
+		if name == "" {
+			name = "???" // Use this if name is not known.
+		}
+		line := fmt.Sprintf("    %s (%d) x %d - reserved %s", name, entry.ID,
+			entry.Count, entry.Reserved.Format("2006-01-02"))
+		if entry.Address != "" {
+			line += " - " + entry.Address
+		}
+		fmt.Println(line)
+	}
+}
+
+func main() {
+	confPtr := flag.String("conf", "", "Configuration file. (Required)")
+	reserve := flag.String("reserve", "", "Reserve this item (name or ID) for the recipient.")
+	handOut := flag.String("handout", "", "Record this item (name or ID) as handed out to the recipient.")
+	cancel := flag.String("cancel", "", "Cancel reservations of this item (name or ID) for the recipient.")
+	recipient := flag.String("to", "", "Recipient of the item. (Required to reserve, hand out, or cancel)")
+	count := flag.Int("count", 1, "Number of the item.")
+	house := flag.String("house", "", "Address of the house holding the item. (Optional)")
+	flag.Parse()
+
+	if *confPtr == "" {
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+	conf := readConfig(*confPtr)
+	itemDB := eqdb.OpenItemDB(conf.ItemDBLoc)
+	ledger, err := eqdb.OpenLedger(conf.LedgerLoc)
+	if err != nil {
+		log.Fatalf("error: Ledger file - %v", err)
+	}
+
+	text := "" // Item for the action
+	actions := 0
+	for _, item := range []string{*reserve, *handOut, *cancel} {
+		if item != "" {
+			text = item
+			actions++
+		}
+	}
+	if actions > 1 {
+		log.Fatalf("error: Only one of -reserve, -handout, or -cancel can be given")
+	}
+
+	now := time.Now().Truncate(time.Second)
+	switch {
+	case actions == 1:
+		if *recipient == "" {
+			log.Fatalf("error: No recipient given with -to")
+		}
+		if *count < 1 {
+			log.Fatalf("error: -count must be at least 1")
+		}
+		id, err := resolveItem(&itemDB, text)
+		if err != nil {
+			log.Fatalf("error: %v", err)
+		}
+		switch {
+		case *reserve != "":
+			ledger.Reserve(id, *count, *recipient, *house, now)
+			fmt.Printf("Reserved %d x %s (%d) for %s\n", *count, itemDB.Name(id), id, *recipient)
+		case *handOut != "":
+			ledger.HandOut(id, *count, *recipient, *house, now)
+			fmt.Printf("Handed out %d x %s (%d) to %s\n", *count, itemDB.Name(id), id, *recipient)
+		default:
+			if ledger.Cancel(id, *recipient) == 0 {
+				log.Fatalf("error: No reservation of %s for %s", text, *recipient)
+			}
+			fmt.Printf("Cancelled reservation of %s (%d) for %s\n", itemDB.Name(id), id, *recipient)
+		}
+		err = ledger.Close()
+		if err != nil {
+			log.Fatalf("error: Saving ledger - %v", err)
+		}
+	default:
+		listPending(&ledger, &itemDB)
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nuttann/equtils/pkg/eqdb"
+	"github.com/nuttann/equtils/pkg/eqfile"
+	"gopkg.in/yaml.v2"
+)
+
+// 'config' holds the locations of the data files and the outputs.
+type config struct {
+	ItemDBLoc    string        // DB location info (currently file name)
+	Logs         []string      // Chat logs to read
+	HistoryLoc   string        // Loot history location (Optional)
+	RaidGap      time.Duration // Time without loot that starts a new raid
+	Raids        []raidDef     // Named raid times (Optional)
+	LootCSV      string        // Where to write every loot record (Optional)
+	RaidCSV      string        // Where to write the per-raid summary (Optional)
+	CharacterCSV string        // Where to write the per-character summary (Optional)
+	JSONOut      string        // Where to write everything as JSON (Optional)
+}
+
+// raidDef is a raid with a name and start and end times. Loot between the
+// times is part of the raid. The times are given as "YYYY-MM-DD HH:MM" in
+// local time.
+type raidDef struct {
+	Name  string
+	Start string
+	End   string
+	start time.Time
+	end   time.Time
+}
+
+// raidTimeLayout is the layout of the configured raid times.
+const raidTimeLayout = "2006-01-02 15:04"
+
+// defaultRaidGap is the raid gap if none is configured.
+const defaultRaidGap = 2 * time.Hour
+
+func readConfig(confFile string) (confData config) {
+	// Read configuration data.
+	buf, err := ioutil.ReadFile(confFile)
+	if err != nil {
+		log.Fatalf("error: Configuration file - %v", err)
+	}
+	err = yaml.Unmarshal(buf, &confData)
+	if err != nil {
+		log.Fatalf("error: Configuration file - %v", err)
+	}
+	if len(confData.Logs) == 0 {
+		log.Fatalf("error: Configuration file - no logs given")
+	}
+	if confData.RaidGap == 0 {
+		confData.RaidGap = defaultRaidGap
+	}
+	for n := range confData.Raids {
+		raid := &confData.Raids[n]
+		if raid.Name == "" {
+			log.Fatalf("error: Configuration file - raids[%d] has no name", n+1)
+		}
+		raid.start, err = time.ParseInLocation(raidTimeLayout, raid.Start, time.Local)
+		if err == nil {
+			raid.end, err = time.ParseInLocation(raidTimeLayout, raid.End, time.Local)
+		}
+		if err != nil || !raid.end.After(raid.start) {
+			log.Fatalf("error: Configuration file - raid \"%s\" needs a start and later end as \"YYYY-MM-DD HH:MM\"", raid.Name)
+		}
+	}
+	return
+}
+
+// resolveID will return the ID of the looted item or 0 if the name does not
+// match exactly one item. Names of several items looted at once may be plural
+// (E.g., "3 Bone Chips"), so the singular is also tried.
+func resolveID(itemDB *eqdb.Items, name string, count int) int {
+	if ids := itemDB.FindName(name); len(ids) == 1 {
+		return ids[0]
+	} else if len(ids) > 1 || count < 2 {
+		return 0
+	}
+	for _, suffix := range []string{"es", "s"} {
+		if strings.HasSuffix(name, suffix) {
+			if ids := itemDB.FindName(strings.TrimSuffix(name, suffix)); len(ids) == 1 {
+				return ids[0]
+			}
+		}
+	}
+	return 0
+}
+
+// readLoot will add the loot in the chat log to the history. "You" is replaced
+// by the character from the log file name. The zone is the last zone entered
+// earlier in the log. It returns the number of loot events found and added,
+// and the number of log lines skipped for not having a valid time stamp.
+func readLoot(history *eqdb.LootHistory, itemDB *eqdb.Items, fname string) (int, int, int, error) {
+	skipped := 0
+	events, err := eqfile.ReadLog(fname)
+	var skip *eqfile.SkippedLinesError
+	if errors.As(err, &skip) {
+		skipped = skip.Skipped
+	} else if err != nil {
+		return 0, 0, 0, err
+	}
+	toon, _, ok := eqfile.ParseLogName(fname)
+	if !ok {
+		return 0, 0, 0, fmt.Errorf("%s: name is not of the form \"eqlog_{TOON}_{SERVER}.txt\"", fname)
+	}
+	var recs []eqdb.LootRecord
+	zone := ""
+	for _, ev := range events {
+		switch ev.Type {
+		case eqfile.EventZone:
+			zone = ev.Target
+		case eqfile.EventLoot:
+			who := ev.Who
+			if who == "You" {
+				who = toon
+			}
+			recs = append(recs, eqdb.LootRecord{
+				Time:      ev.Time,
+				Character: who,
+				Item:      ev.Item,
+				ID:        resolveID(itemDB, ev.Item, ev.Count),
+				Count:     ev.Count,
+				Zone:      zone,
+				From:      ev.From,
+			})
+		}
+	}
+	return len(recs), history.AddLog(recs), skipped, nil
+}
+
+func main() {
+	confPtr := flag.String("conf", "", "Configuration file. (Required)")
+	sincePtr := flag.String("since", "", "Only report loot from this date on. (YYYY-MM-DD)")
+	flag.Parse()
+
+	if *confPtr == "" {
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+	// Get file paths to necessary data files.
+	conf := readConfig(*confPtr)
+	var since time.Time
+	if *sincePtr != "" {
+		var err error
+		since, err = time.ParseInLocation("2006-01-02", *sincePtr, time.Local)
+		if err != nil {
+			log.Fatalf("error: -since must be given as YYYY-MM-DD")
+		}
+	}
+
+	itemDB := eqdb.OpenItemDB(conf.ItemDBLoc)
+	history := eqdb.LootHistory{}
+	if conf.HistoryLoc != "" {
+		var err error
+		history, err = eqdb.OpenLootHistory(conf.HistoryLoc)
+		if err != nil {
+			log.Fatalf("error: Loot history file - %v", err)
+		}
+	}
+
+	failed := false
+	for _, fname := range conf.Logs {
+		found, added, skipped, err := readLoot(&history, &itemDB, fname)
+		if err != nil {
+			fmt.Printf("error: %v\n", err) // Keep going with the other logs.
+			failed = true
+			continue
+		}
+		fmt.Printf("%s - %d loot, %d new\n", fname, found, added)
+		if skipped > 0 {
+			fmt.Printf("warning: %s - skipped %d lines without a valid time stamp\n", fname, skipped)
+		}
+	}
+	if conf.HistoryLoc != "" {
+		if err := history.Close(); err != nil {
+			log.Fatalf("error: Loot history file - %v", err)
+		}
+	}
+
+	report := buildReport(conf, history.Between(since, time.Time{}))
+	unknown := 0
+	for _, rec := range report.Loot {
+		if rec.ID == 0 {
+			unknown++
+		}
+	}
+	if unknown > 0 {
+		fmt.Printf("%d loot records have names not in the item DB.\n", unknown)
+	}
+	writeOutputs(conf, report)
+	if failed {
+		os.Exit(1)
+	}
+}
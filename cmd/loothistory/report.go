@@ -0,0 +1,301 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nuttann/equtils/pkg/eqdb"
+	"github.com/nuttann/equtils/pkg/eqfile"
+)
+
+// Loot is grouped into raids. Loot within a configured raid's times is part of
+// that raid. Other loot is grouped by zone, and a new raid starts whenever the
+// zone changes or there was no loot for the raid gap. These raids are named by
+// the time of the first loot and the zone (E.g., "2020-10-19 20:15 The Plane
+// of Fear").
+
+// lootReport holds every loot record along with the per-raid and
+// per-character summaries. Each output is produced from this.
+type lootReport struct {
+	Loot       []lootEntry       `json:"loot"`
+	Raids      []raidSummary     `json:"raids"`
+	Characters []characterReport `json:"characters"`
+}
+
+// lootEntry is a loot record along with the raid it is part of.
+type lootEntry struct {
+	Time      time.Time `json:"time"`
+	Raid      string    `json:"raid"`
+	Zone      string    `json:"zone"`
+	Character string    `json:"character"`
+	ID        int       `json:"id"` // 0 if the name is not in the item DB
+	Item      string    `json:"item"`
+	Count     int       `json:"count"`
+	From      string    `json:"from"`
+}
+
+// lootItem is the total count of an item looted.
+type lootItem struct {
+	ID    int    `json:"id"`
+	Item  string `json:"item"`
+	Count int    `json:"count"`
+}
+
+// characterLoot is the loot of a character in a raid.
+type characterLoot struct {
+	Character string     `json:"character"`
+	Items     []lootItem `json:"items"`
+}
+
+// raidSummary is the loot of each character in a raid.
+type raidSummary struct {
+	Name       string          `json:"name"`
+	Zone       string          `json:"zone"` // Zone of the first loot
+	Start      time.Time       `json:"start"`
+	End        time.Time       `json:"end"` // Time of the last loot
+	Count      int             `json:"count"`
+	Characters []characterLoot `json:"characters"`
+}
+
+// characterReport is the loot of a character over all raids.
+type characterReport struct {
+	Character string     `json:"character"`
+	Raids     int        `json:"raids"` // Raids with loot
+	Count     int        `json:"count"`
+	Last      time.Time  `json:"last"`
+	Items     []lootItem `json:"items"`
+}
+
+// raidFor will return the name of the configured raid at the time or "" if
+// there is none.
+func raidFor(conf config, t time.Time) string {
+	for _, raid := range conf.Raids {
+		if !t.Before(raid.start) && t.Before(raid.end) {
+			return raid.Name
+		}
+	}
+	return ""
+}
+
+// buildReport will group the loot records (sorted by time) into raids and
+// summarize them by raid and character.
+func buildReport(conf config, records []eqdb.LootRecord) lootReport {
+	report := lootReport{Loot: []lootEntry{}, Raids: []raidSummary{}, Characters: []characterReport{}}
+	var raid *raidSummary
+	for _, rec := range records {
+		name := raidFor(conf, rec.Time)
+		newRaid := raid == nil || raid.Name != name
+		if name == "" {
+			newRaid = raid == nil || raidFor(conf, raid.Start) != "" || raid.Zone != rec.Zone ||
+				rec.Time.Sub(raid.End) > conf.RaidGap
+			if newRaid {
+				zone := rec.Zone
+				if zone == "" {
+					zone = "Unknown zone"
+				}
+				name = rec.Time.Format("2006-01-02 15:04 ") + zone
+			} else {
+				name = raid.Name
+			}
+		}
+		if newRaid {
+			report.Raids = append(report.Raids, raidSummary{Name: name, Zone: rec.Zone, Start: rec.Time})
+			raid = &report.Raids[len(report.Raids)-1]
+		}
+		raid.End = rec.Time
+		raid.Count += rec.Count
+		raid.Characters = addLoot(raid.Characters, rec)
+		report.Loot = append(report.Loot, lootEntry{rec.Time, name, rec.Zone,
+			rec.Character, rec.ID, rec.Item, rec.Count, rec.From})
+	}
+
+	byToon := make(map[string]*characterReport)
+	toonRaids := make(map[string]map[string]bool)
+	for _, entry := range report.Loot {
+		key := strings.ToLower(entry.Character)
+		c, ok := byToon[key]
+		if !ok {
+			c = &characterReport{Character: entry.Character}
+			byToon[key] = c
+			toonRaids[key] = make(map[string]bool)
+		}
+		toonRaids[key][entry.Raid] = true
+		c.Raids = len(toonRaids[key])
+		c.Count += entry.Count
+		c.Last = entry.Time
+		c.Items = addItem(c.Items, entry.ID, entry.Item, entry.Count)
+	}
+	for _, c := range byToon {
+		report.Characters = append(report.Characters, *c)
+	}
+	sort.Slice(report.Characters, func(i, j int) bool {
+		return report.Characters[i].Character < report.Characters[j].Character
+	})
+	for n := range report.Raids {
+		sort.Slice(report.Raids[n].Characters, func(i, j int) bool {
+			return report.Raids[n].Characters[i].Character < report.Raids[n].Characters[j].Character
+		})
+	}
+	return report
+}
+
+// addLoot will add the record to the loot of its character.
+func addLoot(loot []characterLoot, rec eqdb.LootRecord) []characterLoot {
+	for n := range loot {
+		if strings.EqualFold(loot[n].Character, rec.Character) {
+			loot[n].Items = addItem(loot[n].Items, rec.ID, rec.Item, rec.Count)
+			return loot
+		}
+	}
+	return append(loot, characterLoot{rec.Character, addItem(nil, rec.ID, rec.Item, rec.Count)})
+}
+
+// addItem will add the count to the item in the list, keeping the order items
+// were first looted. Items are matched by ID if known (E.g., "Chain" and
+// "Chains") or else by name.
+func addItem(items []lootItem, id int, name string, count int) []lootItem {
+	for n := range items {
+		if (id != 0 && items[n].ID == id) || strings.EqualFold(items[n].Item, name) {
+			items[n].Count += count
+			return items
+		}
+	}
+	return append(items, lootItem{id, name, count})
+}
+
+// itemList will return the items as text (E.g., "Shard of Fear; Bone Chips
+// x3").
+func itemList(items []lootItem) string {
+	var list []string
+	for _, item := range items {
+		if item.Count == 1 {
+			list = append(list, item.Item)
+		} else {
+			list = append(list, fmt.Sprintf("%s x%d", item.Item, item.Count))
+		}
+	}
+	return strings.Join(list, "; ")
+}
+
+// csvTimeLayout is the layout of times in the CSV outputs. Spreadsheets read
+// this as a date and time.
+const csvTimeLayout = "2006-01-02 15:04:05"
+
+// writeLootCSV will output every loot record.
+func writeLootCSV(w *bufio.Writer, report lootReport) error {
+	out := csv.NewWriter(w)
+	err := out.Write([]string{"time", "raid", "zone", "character", "id", "item", "count", "from"})
+	for _, e := range report.Loot {
+		if err != nil {
+			return err
+		}
+		err = out.Write([]string{e.Time.Format(csvTimeLayout), e.Raid, e.Zone,
+			e.Character, strconv.Itoa(e.ID), e.Item, strconv.Itoa(e.Count), e.From})
+	}
+	if err != nil {
+		return err
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// writeRaidCSV will output one line per character in each raid.
+func writeRaidCSV(w *bufio.Writer, report lootReport) error {
+	out := csv.NewWriter(w)
+	err := out.Write([]string{"raid", "zone", "start", "end", "character", "count", "items"})
+	for _, raid := range report.Raids {
+		for _, c := range raid.Characters {
+			if err != nil {
+				return err
+			}
+			count := 0
+			for _, item := range c.Items {
+				count += item.Count
+			}
+			err = out.Write([]string{raid.Name, raid.Zone, raid.Start.Format(csvTimeLayout),
+				raid.End.Format(csvTimeLayout), c.Character, strconv.Itoa(count), itemList(c.Items)})
+		}
+	}
+	if err != nil {
+		return err
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// writeCharacterCSV will output one line per character.
+func writeCharacterCSV(w *bufio.Writer, report lootReport) error {
+	out := csv.NewWriter(w)
+	err := out.Write([]string{"character", "raids", "count", "last", "items"})
+	for _, c := range report.Characters {
+		if err != nil {
+			return err
+		}
+		err = out.Write([]string{c.Character, strconv.Itoa(c.Raids), strconv.Itoa(c.Count),
+			c.Last.Format(csvTimeLayout), itemList(c.Items)})
+	}
+	if err != nil {
+		return err
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// writeJSON will output the whole report as indented JSON.
+func writeJSON(w *bufio.Writer, report lootReport) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(report)
+}
+
+// writeOutputs will write the report to each output configured. All outputs
+// are written to temporary files first. The original files are only replaced
+// once all were written, so a failure leaves the previous outputs in place.
+func writeOutputs(conf config, report lootReport) {
+	var fnames, temps []string
+	for _, out := range []struct {
+		fname string
+		write func(w *bufio.Writer, report lootReport) error
+	}{
+		{conf.LootCSV, writeLootCSV},
+		{conf.RaidCSV, writeRaidCSV},
+		{conf.CharacterCSV, writeCharacterCSV},
+		{conf.JSONOut, writeJSON},
+	} {
+		if out.fname == "" {
+			continue
+		}
+		temp, err := eqfile.WriteTemp(out.fname, func(w *bufio.Writer) error {
+			return out.write(w, report)
+		})
+		if err != nil {
+			for _, t := range temps {
+				os.Remove(t)
+			}
+			log.Fatalf("error: Writing output file %s - %v", out.fname, err)
+		}
+		fnames = append(fnames, out.fname)
+		temps = append(temps, temp)
+	}
+	for n, fname := range fnames {
+		if err := os.Rename(temps[n], fname); err != nil {
+			for _, t := range temps[n:] {
+				os.Remove(t)
+			}
+			log.Fatalf("error: Replacing output file %s - %v", fname, err)
+		}
+	}
+}
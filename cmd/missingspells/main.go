@@ -0,0 +1,279 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/nuttann/equtils/pkg/eqdb"
+	"github.com/nuttann/equtils/pkg/eqfile"
+	"gopkg.in/yaml.v2"
+)
+
+// 'config' holds the files of each character.
+type config struct {
+	IndexLoc   string // Inventory index location (currently file name) (Optional)
+	Characters []character
+}
+
+// character holds the files of a character. Characters without a missing
+// spells file (E.g., mules) only have their inventory searched for scrolls.
+type character struct {
+	Name          string
+	Server        string // Scrolls are only found on this server (Required if there is an index)
+	Level         int    // Spells above this level are not listed (Optional)
+	MissingSpells string // "/outputfile missingspells" file (Optional)
+	Spellbook     string // "/outputfile spellbook" file (Optional)
+	Inventory     string // "/output inventory" file (Optional)
+}
+
+func readConfig(confFile string) (confData config) {
+	// Read configuration data.
+	buf, err := ioutil.ReadFile(confFile)
+	if err != nil {
+		log.Fatalf("error: Configuration file - %v", err)
+	}
+	err = yaml.Unmarshal(buf, &confData)
+	if err != nil {
+		log.Fatalf("error: Configuration file - %v", err)
+	}
+	if len(confData.Characters) == 0 {
+		log.Fatalf("error: Configuration file - no characters given")
+	}
+	for n, c := range confData.Characters {
+		if c.Name == "" {
+			log.Fatalf("error: Configuration file - characters[%d] has no name", n+1)
+		}
+		// The index keys on the server, so a character without one would be
+		// held twice if another program gave its server.
+		if confData.IndexLoc != "" && c.Server == "" {
+			log.Fatalf("error: Configuration file - characters[%d] (%s) needs a server when indexloc is given", n+1, c.Name)
+		}
+	}
+	return
+}
+
+// scrollPrefixes are the prefixes of the names of items that teach a spell.
+var scrollPrefixes = []string{"Spell: ", "Song: "}
+
+// holder is a character holding a scroll and where it is.
+type holder struct {
+	Character string `json:"character"`
+	Server    string `json:"server,omitempty"`
+	Location  string `json:"location"`
+}
+
+// missingSpell is a missing spell and the characters holding its scroll.
+type missingSpell struct {
+	Level   int      `json:"level"`
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	Holders []holder `json:"holders"`
+}
+
+// toonSpells is the missing spells of a character.
+type toonSpells struct {
+	Character string         `json:"character"`
+	Level     int            `json:"level,omitempty"`
+	Missing   []missingSpell `json:"missing"`
+}
+
+// scrolls maps the lower case names of spells to where their scrolls are.
+type scrolls map[string][]holder
+
+// readScrolls will add the scrolls in the inventory of the character.
+func (s scrolls) readScrolls(c character) error {
+	items, err := eqfile.ReadInventory(c.Inventory)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		if name, ok := scrollName(item.Name); ok {
+			s[name] = append(s[name], holder{c.Name, c.Server, item.Loc})
+		}
+	}
+	return nil
+}
+
+// scrollName will return the lower case name of the spell taught by the item
+// and true, or false if the item does not teach a spell.
+func scrollName(item string) (string, bool) {
+	for _, prefix := range scrollPrefixes {
+		if strings.HasPrefix(item, prefix) {
+			return strings.ToLower(strings.TrimPrefix(item, prefix)), true
+		}
+	}
+	return "", false
+}
+
+// indexScrolls will return the scrolls held in the inventory index. This
+// includes scrolls in houses and those of characters whose dump files are
+// gone.
+func indexScrolls(index *eqdb.InventoryIndex) scrolls {
+	held := make(scrolls)
+	for _, h := range index.Find(eqdb.InvQuery{}) {
+		if name, ok := scrollName(h.Name); ok {
+			held[name] = append(held[name], holder{h.Character, h.Server, h.Location})
+		}
+	}
+	return held
+}
+
+// findMissing will return the missing spells of the character that are not in
+// its spellbook and are at or below its level. The spellbook is checked in
+// case the missing spells file is older. Only scrolls held on the server of
+// the character are listed, since they cannot be traded across servers.
+func findMissing(c character, held scrolls) (toonSpells, error) {
+	toon := toonSpells{Character: c.Name, Level: c.Level, Missing: []missingSpell{}}
+	missing, err := eqfile.ReadMissingSpells(c.MissingSpells)
+	if err != nil {
+		return toon, err
+	}
+	known := make(map[string]bool)
+	if c.Spellbook != "" {
+		book, err := eqfile.ReadSpellbook(c.Spellbook)
+		if err != nil {
+			return toon, err
+		}
+		for _, spell := range book {
+			known[strings.ToLower(spell.Name)] = true
+		}
+	}
+	for _, spell := range missing {
+		if known[strings.ToLower(spell.Name)] || (c.Level > 0 && spell.Level > c.Level) {
+			continue
+		}
+		holders := []holder{}
+		for _, h := range held[strings.ToLower(spell.Name)] {
+			if strings.EqualFold(h.Server, c.Server) {
+				holders = append(holders, h)
+			}
+		}
+		toon.Missing = append(toon.Missing, missingSpell{spell.Level, spell.Name, spell.Type, holders})
+	}
+	sort.SliceStable(toon.Missing, func(i, j int) bool { return toon.Missing[i].Level < toon.Missing[j].Level })
+	return toon, nil
+}
+
+// writeText will output the missing spells of each character along with where
+// their scrolls are.
+func writeText(w io.Writer, toons []toonSpells) {
+	for _, toon := range toons {
+		level := ""
+		if toon.Level > 0 {
+			level = fmt.Sprintf(" (level %d)", toon.Level)
+		}
+		fmt.Fprintf(w, "==== %s%s - %d missing\n", toon.Character, level, len(toon.Missing))
+		for _, spell := range toon.Missing {
+			var where []string
+			for _, h := range spell.Holders {
+				where = append(where, h.Character+": "+h.Location)
+			}
+			held := ""
+			if len(where) > 0 {
+				held = " - scroll held by " + strings.Join(where, ", ")
+			}
+			fmt.Fprintf(w, "    %3d %s%s\n", spell.Level, spell.Name, held)
+		}
+	}
+}
+
+func main() {
+	confPtr := flag.String("conf", "", "Configuration file. (Required)")
+	toonPtr := flag.String("toon", "", "Only list the missing spells of this character.")
+	heldPtr := flag.Bool("held", false, "Only list missing spells with a scroll held by a character.")
+	jsonPtr := flag.Bool("json", false, "Write the list as JSON.")
+	flag.Parse()
+
+	if *confPtr == "" {
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+	conf := readConfig(*confPtr)
+
+	// Errors are printed to stderr so they do not mix with the JSON output.
+	failed := false
+	held := make(scrolls)
+	var index eqdb.InventoryIndex
+	if conf.IndexLoc != "" {
+		var err error
+		if index, err = eqdb.OpenInventoryIndex(conf.IndexLoc); err != nil {
+			log.Fatalf("error: Inventory index file - %v", err)
+		}
+	}
+	for _, c := range conf.Characters {
+		if c.Inventory == "" {
+			continue
+		}
+		var err error
+		if conf.IndexLoc != "" {
+			_, err = index.AddInventoryFile(c.Name, c.Server, c.Inventory)
+		} else {
+			err = held.readScrolls(c)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s - %v\n", c.Name, err)
+			failed = true
+		}
+	}
+	if conf.IndexLoc != "" {
+		if err := index.Close(); err != nil {
+			log.Fatalf("error: Inventory index file - %v", err)
+		}
+		held = indexScrolls(&index)
+	}
+	toons := []toonSpells{}
+	found := false
+	for _, c := range conf.Characters {
+		if *toonPtr != "" && !strings.EqualFold(c.Name, *toonPtr) {
+			continue
+		}
+		found = true
+		if c.MissingSpells == "" {
+			continue
+		}
+		toon, err := findMissing(c, held)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s - %v\n", c.Name, err)
+			failed = true
+			continue
+		}
+		if *heldPtr {
+			var missing []missingSpell
+			for _, spell := range toon.Missing {
+				if len(spell.Holders) > 0 {
+					missing = append(missing, spell)
+				}
+			}
+			toon.Missing = append([]missingSpell{}, missing...)
+		}
+		toons = append(toons, toon)
+	}
+	if !found {
+		log.Fatalf("error: No character named \"%s\" in the configuration file", *toonPtr)
+	}
+
+	if *jsonPtr {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(toons); err != nil {
+			log.Fatalf("error: Writing list - %v", err)
+		}
+	} else {
+		writeText(os.Stdout, toons)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
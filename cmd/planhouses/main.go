@@ -0,0 +1,322 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/nuttann/equtils/pkg/eqdb"
+	"github.com/nuttann/equtils/pkg/eqfile"
+	"gopkg.in/yaml.v2"
+)
+
+// 'config' holds the planning data read from the YAML configuration file.
+type config struct {
+	QuestsFile string         // File with exp - zone - quest - item ID mappings
+	Growth     int            // Percent of each house to keep free for growth
+	Expansions []string       // Expansions to plan (Optional - default is all)
+	HouseTypes map[string]int // Storage slots for each house type
+	Houses     []House        // Houses to fill, in order
+}
+
+// House is a house that can be used for collections.
+type House struct {
+	Fname   string // Real estate file name
+	Address string // Property string (From the "/output realestate" file.)
+	Type    string // House type (Optional - found in the address if not given)
+	Slots   int    // Storage slots (Optional - overrides the house type)
+}
+
+// zonePlan is a zone to place in a house. Zones are not split between houses.
+type zonePlan struct {
+	Exp     string
+	Zone    string
+	Slots   int // Slots needed to hold one stack of every quest item
+	Stocked int // Slots holding quest items now
+}
+
+// housePlan is the planned contents of a house.
+type housePlan struct {
+	House
+	Capacity int // Storage slots in the house
+	Usable   int // Slots that can be planned (Capacity less growth room)
+	Used     int // Slots planned
+	Stocked  int // Planned slots holding items now
+	Zones    []zonePlan
+}
+
+func readConfig(confFile string) (confData config) {
+	// Read configuration data.
+	buf, err := ioutil.ReadFile(confFile)
+	if err != nil {
+		log.Fatalf("error: Configuration file - %v", err)
+	}
+	err = yaml.Unmarshal(buf, &confData)
+	if err != nil {
+		log.Fatalf("error: Configuration file - %v", err)
+	}
+	if confData.Growth < 0 || confData.Growth >= 100 {
+		log.Fatalf("error: Configuration file - growth must be from 0 to 99")
+	}
+	return
+}
+
+// houseCapacity will return the storage slots for the house. Without a type
+// or slots given, the longest house type found in the address is used.
+func houseCapacity(conf config, house House) (int, error) {
+	if house.Slots > 0 {
+		return house.Slots, nil
+	}
+	if house.Type != "" {
+		slots, ok := conf.HouseTypes[house.Type]
+		if !ok {
+			return 0, fmt.Errorf("%s: unknown house type \"%s\"", house.Address, house.Type)
+		}
+		return slots, nil
+	}
+	found := ""
+	for houseType := range conf.HouseTypes {
+		if strings.Contains(house.Address, houseType) && len(houseType) > len(found) {
+			found = houseType
+		}
+	}
+	if found == "" {
+		return 0, fmt.Errorf("%s: no house type or slots given", house.Address)
+	}
+	return conf.HouseTypes[found], nil
+}
+
+// readStock will return the number of stacks of each item stored in the
+// houses. Only the configured houses are counted, since a real-estate file
+// also lists the other houses of the account. Each file is only read once.
+func readStock(houses []House) map[int]int {
+	addresses := make(map[string]map[string]bool) // Configured houses in each file
+	var fnames []string
+	for _, house := range houses {
+		if addresses[house.Fname] == nil {
+			addresses[house.Fname] = make(map[string]bool)
+			fnames = append(fnames, house.Fname)
+		}
+		addresses[house.Fname][house.Address] = true
+	}
+	stacks := make(map[int]int)
+	for _, fname := range fnames {
+		reData, err := eqfile.ReadRE(fname)
+		if err != nil {
+			log.Fatalf("error: Reading house file - %v", err)
+		}
+		for _, entry := range reData {
+			if entry.RELoc == "Plot" && entry.Status == "Stored" && addresses[fname][entry.REName] {
+				stacks[entry.ID]++
+			}
+		}
+	}
+	return stacks
+}
+
+// planZones will return the zones to place in quest data order. A zone needs
+// one slot for each quest item, or more if the item is stored in more stacks
+// now. The stacks stored now are only counted in the first zone using the
+// item, since they can only be in one house.
+func planZones(conf config, quests []eqdb.QuestExp, stacks map[int]int) []zonePlan {
+	var zones []zonePlan
+	counted := make(map[int]bool) // Items with stored stacks already counted
+	for _, exp := range quests {
+		if len(conf.Expansions) > 0 && !contains(conf.Expansions, exp.Name) {
+			continue
+		}
+		for _, zone := range exp.Zones {
+			zp := zonePlan{Exp: exp.Name, Zone: zone.Name}
+			for _, quest := range zone.Quests {
+				ids, err := quest.IDs()
+				if err != nil {
+					log.Fatalf("error: Quest data file - %s / %s / %s - %v",
+						exp.Name, zone.Name, quest.Name, err)
+				}
+				for _, id := range ids {
+					zp.Slots++
+					if stacks[id] > 0 && !counted[id] {
+						counted[id] = true
+						zp.Slots += stacks[id] - 1
+						zp.Stocked += stacks[id]
+					}
+				}
+			}
+			zones = append(zones, zp)
+		}
+	}
+	return zones
+}
+
+// planHouses will place the zones in the houses. Houses are filled in order so
+// the zones of an expansion stay in neighbouring houses. A zone too big for
+// the usable slots of a house goes in an empty house by itself if it fits in
+// the full capacity. The zones that could not be placed are returned.
+func planHouses(houses []housePlan, zones []zonePlan) (unplaced []zonePlan) {
+	cur := 0
+	for _, zone := range zones {
+		placed := false
+		for ; cur < len(houses); cur++ {
+			hp := &houses[cur]
+			empty := len(hp.Zones) == 0
+			if hp.Used+zone.Slots <= hp.Usable || (empty && zone.Slots <= hp.Capacity) {
+				hp.Zones = append(hp.Zones, zone)
+				hp.Used += zone.Slots
+				hp.Stocked += zone.Stocked
+				placed = true
+				break
+			}
+			if empty {
+				break // Too big for this house even when empty.
+			}
+		}
+		if !placed {
+			unplaced = append(unplaced, zone)
+		}
+	}
+	return unplaced
+}
+
+// percent returns n as a whole percentage of total.
+func percent(n, total int) int {
+	if total == 0 {
+		return 0
+	}
+	return n * 100 / total
+}
+
+// writePlan will write the plan as the "houses" section of a "collectstoweb"
+// configuration file. The fullness of each house is given in comments.
+func writePlan(w io.Writer, conf config, houses []housePlan, allZones map[string]int, unplaced []zonePlan) {
+	fmt.Fprintf(w, "# House plan - %d%% of each house is kept free for growth.\n", conf.Growth)
+	fmt.Fprintln(w, "houses:")
+	for _, hp := range houses {
+		if len(hp.Zones) == 0 {
+			continue
+		}
+		note := ""
+		if hp.Used > hp.Usable {
+			note = " - no room for growth"
+		}
+		fmt.Fprintf(w, "  # %d of %d slots planned (%d%%) - %d in use now (%d%%)%s\n",
+			hp.Used, hp.Capacity, percent(hp.Used, hp.Capacity),
+			hp.Stocked, percent(hp.Stocked, hp.Capacity), note)
+		fmt.Fprintf(w, "  - fname: %q\n", hp.Fname)
+		fmt.Fprintf(w, "    address: %q\n", hp.Address)
+		if hp.Slots > 0 || (hp.Type != "" && !strings.Contains(hp.Address, hp.Type)) {
+			// "collectstoweb" cannot tell the capacity from the address.
+			fmt.Fprintf(w, "    slots: %d\n", hp.Capacity)
+		}
+		fmt.Fprintln(w, "    expansions:")
+		for i := 0; i < len(hp.Zones); {
+			exp := hp.Zones[i].Exp
+			j := i
+			for j < len(hp.Zones) && hp.Zones[j].Exp == exp {
+				j++
+			}
+			fmt.Fprintf(w, "      - name: %q\n", exp)
+			if j-i < allZones[exp] {
+				// Only part of the expansion is in this house.
+				fmt.Fprintln(w, "        zones:")
+				for _, zone := range hp.Zones[i:j] {
+					fmt.Fprintf(w, "          - %q\n", zone.Zone)
+				}
+			}
+			i = j
+		}
+		fmt.Fprintln(w)
+	}
+	for _, hp := range houses {
+		if len(hp.Zones) == 0 {
+			fmt.Fprintf(w, "# Unused - %s (%d slots)\n", hp.Address, hp.Capacity)
+		}
+	}
+	for _, zone := range unplaced {
+		fmt.Fprintf(w, "# Not placed - %s / %s (%d slots)\n", zone.Exp, zone.Zone, zone.Slots)
+	}
+}
+
+// contains returns true if the string is in the list.
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func main() {
+	confPtr := flag.String("conf", "", "Configuration file. (Required)")
+	outPtr := flag.String("out", "", "File to write the plan to. (Default is the terminal)")
+	flag.Parse()
+
+	if *confPtr == "" {
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+	conf := readConfig(*confPtr)
+	quests, err := eqdb.ReadQuests(conf.QuestsFile)
+	if err != nil {
+		log.Fatalf("error: Quest data file - %v", err)
+	}
+	for _, name := range conf.Expansions {
+		found := false
+		for _, exp := range quests {
+			found = found || exp.Name == name
+		}
+		if !found {
+			log.Fatalf("error: Configuration file - unknown expansion \"%s\"", name)
+		}
+	}
+
+	var houses []housePlan
+	for _, house := range conf.Houses {
+		capacity, err := houseCapacity(conf, house)
+		if err != nil {
+			log.Fatalf("error: Configuration file - %v", err)
+		}
+		houses = append(houses, housePlan{
+			House:    house,
+			Capacity: capacity,
+			Usable:   capacity * (100 - conf.Growth) / 100,
+		})
+	}
+	zones := planZones(conf, quests, readStock(conf.Houses))
+	allZones := make(map[string]int) // Number of zones in each expansion
+	for _, zone := range zones {
+		allZones[zone.Exp]++
+	}
+	unplaced := planHouses(houses, zones)
+
+	w := bufio.NewWriter(os.Stdout)
+	var f *os.File
+	if *outPtr != "" {
+		f, err = os.Create(*outPtr)
+		if err != nil {
+			log.Fatalf("error: Creating plan file - %v", err)
+		}
+		w = bufio.NewWriter(f)
+	}
+	writePlan(w, conf, houses, allZones, unplaced)
+	err = w.Flush()
+	if err == nil && f != nil {
+		err = f.Close()
+	}
+	if err != nil {
+		log.Fatalf("error: Writing plan - %v", err)
+	}
+	if len(unplaced) > 0 {
+		fmt.Fprintf(os.Stderr, "warning: %d zones did not fit in the houses\n", len(unplaced))
+		os.Exit(1)
+	}
+}
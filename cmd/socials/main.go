@@ -0,0 +1,241 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/brianholland99/intlist"
+	"github.com/nuttann/equtils/pkg/eqfile"
+	"gopkg.in/yaml.v2"
+)
+
+// 'config' holds the UI files of each character.
+type config struct {
+	Characters []character
+	BackupDir  string // Where backups are written (Optional, default same folder)
+}
+
+// character is a character and its UI file ("{TOON}_{SERVER}.ini").
+type character struct {
+	Name string
+	INI  string
+}
+
+func readConfig(confFile string) (confData config) {
+	// Read configuration data.
+	buf, err := ioutil.ReadFile(confFile)
+	if err != nil {
+		log.Fatalf("error: Configuration file - %v", err)
+	}
+	err = yaml.Unmarshal(buf, &confData)
+	if err != nil {
+		log.Fatalf("error: Configuration file - %v", err)
+	}
+	for n, c := range confData.Characters {
+		if c.Name == "" || c.INI == "" {
+			log.Fatalf("error: Configuration file - characters[%d] needs a name and ini", n+1)
+		}
+	}
+	return
+}
+
+// findINI will return the UI file of the character (ignoring case).
+func findINI(conf config, name string) string {
+	for _, c := range conf.Characters {
+		if strings.EqualFold(c.Name, name) {
+			return c.INI
+		}
+	}
+	log.Fatalf("error: No character named \"%s\" in the configuration file", name)
+	return ""
+}
+
+// readINI will return the UI file of the character.
+func readINI(conf config, name string) *eqfile.INI {
+	ini, err := eqfile.ReadINI(findINI(conf, name))
+	if err != nil {
+		log.Fatalf("error: UI file - %v", err)
+	}
+	return ini
+}
+
+// onPages will return the socials on the pages. All socials are returned if no
+// pages are given.
+func onPages(socials []eqfile.Social, pages map[int]bool) []eqfile.Social {
+	if len(pages) == 0 {
+		return socials
+	}
+	var result []eqfile.Social
+	for _, s := range socials {
+		if pages[s.Page] {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// describe will return the social as text (E.g., "Buff [/cast 1 | /sit]").
+func describe(s eqfile.Social) string {
+	return fmt.Sprintf("%s [%s]", s.Name, strings.Join(s.Lines, " | "))
+}
+
+// listSocials will print the socials of the character.
+func listSocials(ini *eqfile.INI, name string, pages map[int]bool) {
+	socials := onPages(ini.Socials(), pages)
+	fmt.Printf("==== %s - %d socials\n", name, len(socials))
+	for _, s := range socials {
+		fmt.Printf("    Page %d Button %d - %s\n", s.Page, s.Button, describe(s))
+	}
+}
+
+// sameSocial returns true if the socials have the same name, color, and
+// lines.
+func sameSocial(a, b eqfile.Social) bool {
+	return a.Name == b.Name && a.Color == b.Color &&
+		strings.Join(a.Lines, "\n") == strings.Join(b.Lines, "\n")
+}
+
+// diffSocials will print the socials that differ between the characters. It
+// returns the number of differences.
+func diffSocials(a *eqfile.INI, aName string, b *eqfile.INI, bName string, pages map[int]bool) int {
+	type pos struct{ Page, Button int }
+	bSocials := make(map[pos]eqfile.Social)
+	for _, s := range onPages(b.Socials(), pages) {
+		bSocials[pos{s.Page, s.Button}] = s
+	}
+	diffs := 0
+	fmt.Printf("==== %s / %s\n", aName, bName)
+	for _, s := range onPages(a.Socials(), pages) {
+		p := pos{s.Page, s.Button}
+		other, ok := bSocials[p]
+		delete(bSocials, p)
+		switch {
+		case !ok:
+			fmt.Printf("    Page %d Button %d - only %s - %s\n", s.Page, s.Button, aName, describe(s))
+		case !sameSocial(s, other):
+			fmt.Printf("    Page %d Button %d - %s - %s\n", s.Page, s.Button, aName, describe(s))
+			fmt.Printf("    %s - %s - %s\n", strings.Repeat(" ", len(fmt.Sprintf("Page %d Button %d", s.Page, s.Button))),
+				bName, describe(other))
+		default:
+			continue
+		}
+		diffs++
+	}
+	for _, s := range onPages(b.Socials(), pages) {
+		if _, ok := bSocials[pos{s.Page, s.Button}]; ok {
+			fmt.Printf("    Page %d Button %d - only %s - %s\n", s.Page, s.Button, bName, describe(s))
+			diffs++
+		}
+	}
+	if diffs == 0 {
+		fmt.Println("    Same")
+	}
+	return diffs
+}
+
+// copySocials will replace the socials on the pages of the target with those
+// of the source. All pages are copied if none are given. Hot buttons are
+// copied as well if asked. It returns the number of socials copied.
+func copySocials(from *eqfile.INI, to *eqfile.INI, pages map[int]bool, hotButtons bool) int {
+	for _, s := range onPages(to.Socials(), pages) {
+		to.DeleteSocial(s.Page, s.Button)
+	}
+	socials := onPages(from.Socials(), pages)
+	for _, s := range socials {
+		to.SetSocial(s)
+	}
+	if hotButtons {
+		for _, b := range to.HotButtons() {
+			to.DeleteHotButton(b.Bar, b.Page, b.Button)
+		}
+		for _, b := range from.HotButtons() {
+			to.SetHotButton(b)
+		}
+	}
+	return len(socials)
+}
+
+func main() {
+	confPtr := flag.String("conf", "", "Configuration file. (Required)")
+	listPtr := flag.String("list", "", "List the socials of this character.")
+	diffPtr := flag.String("diff", "", "Compare the socials of this character with the -with character.")
+	withPtr := flag.String("with", "", "Character to compare with.")
+	copyPtr := flag.String("copy", "", "Copy the socials of this character to the -to characters.")
+	toPtr := flag.String("to", "", "Characters to copy to. (E.g., \"Gallin,Bob\")")
+	pagesPtr := flag.String("pages", "", "Only use these socials pages. (E.g., \"1,3...5\")")
+	hotPtr := flag.Bool("hotbuttons", false, "Also copy the hot buttons.")
+	flag.Parse()
+
+	if *confPtr == "" {
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+	conf := readConfig(*confPtr)
+	pages := make(map[int]bool)
+	if *pagesPtr != "" {
+		list, err := intlist.Parse(*pagesPtr)
+		if err != nil {
+			log.Fatalf("error: -pages - %v", err)
+		}
+		for _, p := range list {
+			pages[p] = true
+		}
+	}
+
+	switch {
+	case *listPtr != "":
+		listSocials(readINI(conf, *listPtr), *listPtr, pages)
+	case *diffPtr != "":
+		if *withPtr == "" {
+			log.Fatalf("error: -diff needs -with")
+		}
+		if diffSocials(readINI(conf, *diffPtr), *diffPtr, readINI(conf, *withPtr), *withPtr, pages) > 0 {
+			os.Exit(1)
+		}
+	case *copyPtr != "":
+		if *toPtr == "" {
+			log.Fatalf("error: -copy needs -to")
+		}
+		if *hotPtr && len(pages) > 0 {
+			log.Fatalf("error: -hotbuttons cannot be used with -pages")
+		}
+		from := readINI(conf, *copyPtr)
+		// Check all targets before any file is changed.
+		var names []string
+		var targets []*eqfile.INI
+		for _, name := range strings.Split(*toPtr, ",") {
+			name = strings.TrimSpace(name)
+			if findINI(conf, name) == findINI(conf, *copyPtr) {
+				log.Fatalf("error: Cannot copy %s to itself", name)
+			}
+			names = append(names, name)
+			targets = append(targets, readINI(conf, name))
+		}
+		now := time.Now()
+		for n, to := range targets {
+			fname := findINI(conf, names[n])
+			bak, err := eqfile.BackupFile(fname, conf.BackupDir, now)
+			if err != nil {
+				log.Fatalf("error: Backing up %s - %v", fname, err)
+			}
+			count := copySocials(from, to, pages, *hotPtr)
+			if err = to.WriteFile(fname); err != nil {
+				log.Fatalf("error: Writing %s - %v (Backup in %s)", fname, err, bak)
+			}
+			fmt.Printf("Copied %d socials to %s (Backup in %s)\n", count, names[n], bak)
+		}
+	default:
+		for _, c := range conf.Characters {
+			listSocials(readINI(conf, c.Name), c.Name, pages)
+		}
+	}
+}
@@ -0,0 +1,293 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/brianholland99/intlist"
+	"github.com/nuttann/equtils/pkg/eqdb"
+	"gopkg.in/yaml.v2"
+)
+
+// 'config' holds the locations of the data files. This uses the same
+// configuration file as the "collectstoweb" command, so other fields in the
+// file are ignored.
+type config struct {
+	QuestsFile  string // File with exp - zone - quest - item ID mappings
+	ItemDBLoc   string // DB location info (currently file name)
+	ProgressLoc string // Character progress location
+}
+
+func readConfig(confFile string) (confData config) {
+	// Read configuration data.
+	buf, err := ioutil.ReadFile(confFile)
+	if err != nil {
+		log.Fatalf("error: Configuration file - %v", err)
+	}
+	err = yaml.Unmarshal(buf, &confData)
+	if err != nil {
+		log.Fatalf("error: Configuration file - %v", err)
+	}
+	if confData.ProgressLoc == "" {
+		log.Fatalf("error: Configuration file - no progressloc given")
+	}
+	return
+}
+
+// resolveItems will return the item IDs for an item name or a list of IDs
+// (E.g., "500...506,519"). The name must match exactly one item in the item
+// DB (ignoring case).
+func resolveItems(itemDB *eqdb.Items, text string) ([]int, error) {
+	if ids, err := intlist.Parse(text); err == nil {
+		return ids, nil
+	}
+	ids := itemDB.FindName(text)
+	switch len(ids) {
+	case 0:
+		return nil, fmt.Errorf("no item named \"%s\" in the item DB", text)
+	case 1:
+		return ids, nil
+	}
+	var names []string
+	for _, id := range ids {
+		names = append(names, strconv.Itoa(id))
+	}
+	return nil, fmt.Errorf("several items named \"%s\" - use an ID (%s)",
+		text, strings.Join(names, ", "))
+}
+
+// findQuest will return the quest for the text (ignoring case). The text is
+// the quest name, optionally after the zone or the expansion and zone (E.g.,
+// "Shard's Landing / Fear in Pieces"). An error is returned if there is no
+// such quest or if several quests match.
+func findQuest(quests []eqdb.QuestExp, text string) (eqdb.CompletedQuest, error) {
+	parts := strings.Split(text, "/")
+	if len(parts) > 3 {
+		return eqdb.CompletedQuest{}, fmt.Errorf("\"%s\" is not of the form [[EXP /] ZONE /] QUEST", text)
+	}
+	for n := range parts {
+		parts[n] = strings.TrimSpace(parts[n])
+	}
+	var found []eqdb.CompletedQuest
+	for _, exp := range quests {
+		for _, zone := range exp.Zones {
+			for _, quest := range zone.Quests {
+				names := []string{exp.Name, zone.Name, quest.Name}
+				match := true
+				for n, part := range parts {
+					match = match && strings.EqualFold(names[3-len(parts)+n], part)
+				}
+				if match {
+					found = append(found, eqdb.CompletedQuest{Exp: exp.Name, Zone: zone.Name, Name: quest.Name})
+				}
+			}
+		}
+	}
+	switch len(found) {
+	case 0:
+		return eqdb.CompletedQuest{}, fmt.Errorf("no quest named \"%s\" in the quest data", text)
+	case 1:
+		return found[0], nil
+	}
+	var names []string
+	for _, quest := range found {
+		names = append(names, quest.String())
+	}
+	return eqdb.CompletedQuest{}, fmt.Errorf("several quests named \"%s\" - add the zone (%s)",
+		text, strings.Join(names, ", "))
+}
+
+// importFile will record the items and quests in the file for the character.
+// Each line holds an item name or IDs, or "quest: NAME" for a completed quest.
+// Blank lines and lines starting with "#" are ignored. Lines that cannot be
+// resolved are returned as problems and the rest are still recorded.
+func importFile(progress *eqdb.Progress, itemDB *eqdb.Items, quests []eqdb.QuestExp, toon string, fname string) ([]string, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var problems []string
+	items, completed := 0, 0
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if strings.HasPrefix(strings.ToLower(text), "quest:") {
+			quest, err := findQuest(quests, strings.TrimSpace(text[len("quest:"):]))
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("line %d: %v", lineNo, err))
+				continue
+			}
+			if progress.CompleteQuest(toon, quest) {
+				completed++
+			}
+			continue
+		}
+		ids, err := resolveItems(itemDB, text)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("line %d: %v", lineNo, err))
+			continue
+		}
+		items += progress.AddItems(toon, ids)
+	}
+	if err := scanner.Err(); err != nil {
+		return problems, err
+	}
+	fmt.Printf("Imported %d new items and %d completed quests for %s\n", items, completed, toon)
+	return problems, nil
+}
+
+// listToons will print a line for each character with recorded progress.
+func listToons(progress *eqdb.Progress) {
+	if len(progress.Toons) == 0 {
+		fmt.Println("No progress recorded.")
+		return
+	}
+	for _, t := range progress.Toons {
+		fmt.Printf("%s - %d items, %d quests completed\n", t.Name, len(t.Items), len(t.Quests))
+	}
+}
+
+// showToon will print the progress of the character for each quest with any
+// progress.
+func showToon(progress *eqdb.Progress, quests []eqdb.QuestExp, name string) {
+	t := progress.Toon(name)
+	if t == nil {
+		fmt.Println("No progress recorded for", name)
+		return
+	}
+	fmt.Println("====", t.Name)
+	for _, exp := range quests {
+		for _, zone := range exp.Zones {
+			for _, quest := range zone.Quests {
+				where := exp.Name + " / " + zone.Name + " / " + quest.Name
+				if t.HasQuest(exp.Name, zone.Name, quest.Name) {
+					fmt.Println(where, "- completed")
+					continue
+				}
+				ids, err := quest.IDs()
+				if err != nil {
+					log.Fatalf("error: Quest data file - %s - %v", where, err)
+				}
+				have := 0
+				for _, id := range ids {
+					if t.HasItem(id) {
+						have++
+					}
+				}
+				if have > 0 {
+					fmt.Printf("%s - %d of %d\n", where, have, len(ids))
+				}
+			}
+		}
+	}
+}
+
+func main() {
+	confPtr := flag.String("conf", "", "Configuration file. (Required)")
+	toon := flag.String("toon", "", "Character to record or show progress for.")
+	collected := flag.String("collected", "", "Record this item (name or IDs) as collected.")
+	uncollect := flag.String("uncollect", "", "Remove this item (name or IDs) from those collected.")
+	complete := flag.String("complete", "", "Record this quest as completed. (E.g., \"Shard's Landing / Fear in Pieces\")")
+	reopen := flag.String("reopen", "", "Remove this quest (name) from those completed.")
+	importPtr := flag.String("import", "", "Record the items and quests listed in this file.")
+	flag.Parse()
+
+	if *confPtr == "" {
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+	conf := readConfig(*confPtr)
+	quests, err := eqdb.ReadQuests(conf.QuestsFile)
+	if err != nil {
+		log.Fatalf("error: Quest data file - %v", err)
+	}
+	itemDB := eqdb.OpenItemDB(conf.ItemDBLoc)
+	progress, err := eqdb.OpenProgress(conf.ProgressLoc)
+	if err != nil {
+		log.Fatalf("error: Progress file - %v", err)
+	}
+
+	actions := 0
+	for _, action := range []string{*collected, *uncollect, *complete, *reopen, *importPtr} {
+		if action != "" {
+			actions++
+		}
+	}
+	if actions > 1 {
+		log.Fatalf("error: Only one of -collected, -uncollect, -complete, -reopen, or -import can be given")
+	}
+	if actions == 0 {
+		if *toon == "" {
+			listToons(&progress)
+		} else {
+			showToon(&progress, quests, *toon)
+		}
+		return
+	}
+	if *toon == "" {
+		log.Fatalf("error: No character given with -toon")
+	}
+
+	var problems []string
+	switch {
+	case *collected != "" || *uncollect != "":
+		ids, err := resolveItems(&itemDB, *collected+*uncollect)
+		if err != nil {
+			log.Fatalf("error: %v", err)
+		}
+		if *collected != "" {
+			n := progress.AddItems(*toon, ids)
+			fmt.Printf("Recorded %d new items as collected by %s\n", n, *toon)
+		} else {
+			n := progress.RemoveItems(*toon, ids)
+			fmt.Printf("Removed %d items from those collected by %s\n", n, *toon)
+		}
+	case *complete != "" || *reopen != "":
+		quest, err := findQuest(quests, *complete+*reopen)
+		if err != nil {
+			log.Fatalf("error: %v", err)
+		}
+		if *complete != "" {
+			if !progress.CompleteQuest(*toon, quest) {
+				log.Fatalf("error: %v is already recorded as completed by %s", quest, *toon)
+			}
+			fmt.Printf("Recorded %v as completed by %s\n", quest, *toon)
+		} else {
+			if !progress.ReopenQuest(*toon, quest) {
+				log.Fatalf("error: %v is not recorded as completed by %s", quest, *toon)
+			}
+			fmt.Printf("Removed %v from those completed by %s\n", quest, *toon)
+		}
+	default:
+		problems, err = importFile(&progress, &itemDB, quests, *toon, *importPtr)
+		if err != nil {
+			log.Fatalf("error: Import file - %v", err)
+		}
+		for _, problem := range problems {
+			fmt.Printf("%s: %s\n", *importPtr, problem)
+		}
+	}
+	err = progress.Close()
+	if err != nil {
+		log.Fatalf("error: Saving progress - %v", err)
+	}
+	if len(problems) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nuttann/equtils/pkg/eqdb"
+)
+
+// The dry run processes all configured files against a copy of the item DB
+// and reports what would change. Nothing is written, so the changes can be
+// reviewed before updating a shared DB.
+
+// dryRunReport lists the changes that would be made to the item DB.
+type dryRunReport struct {
+	New       []newItem    `json:"new"`       // Items not in the DB
+	Names     []itemChange `json:"names"`     // Name changes
+	Icons     []itemChange `json:"icons"`     // Icon ID changes
+	Conflicts []conflict   `json:"conflicts"` // Sources that disagree
+	Failed    []fileError  `json:"failed"`    // Files that could not be read
+}
+
+// newItem is an item that would be added to the DB.
+type newItem struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	IconID int    `json:"iconid,omitempty"`
+	Source string `json:"source"` // File the name came from
+}
+
+// itemChange is a change to a field of an item in the DB. Icon IDs are given
+// as strings.
+type itemChange struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"` // Item name after the change
+	Old    string `json:"old"`
+	New    string `json:"new"`
+	Source string `json:"source"` // File the new value came from
+}
+
+// dryRun will update a copy of the item DB from the sources and return the
+// changes that would be made. Files that cannot be read are listed in the
+// report.
+func dryRun(itemDB *eqdb.Items, conf config, srcs []source) dryRunReport {
+	work := eqdb.Items{DB: make(map[int]eqdb.Item)}
+	for id, item := range itemDB.DB {
+		work.DB[id] = item
+	}
+	result := update(&work, conf, srcs, make(map[string]fileData))
+
+	report := dryRunReport{New: []newItem{}, Names: []itemChange{}, Icons: []itemChange{},
+		Failed: []fileError{}}
+	report.Failed = append(report.Failed, result.Failed...)
+	for id, after := range work.DB {
+		before, ok := itemDB.DB[id]
+		if !ok {
+			report.New = append(report.New, newItem{id, after.Name, after.IconID, result.NameSrc[id]})
+			continue
+		}
+		if after.Name != before.Name {
+			report.Names = append(report.Names, itemChange{id, after.Name, before.Name, after.Name, result.NameSrc[id]})
+		}
+		if after.IconID != before.IconID {
+			report.Icons = append(report.Icons, itemChange{id, after.Name,
+				strconv.Itoa(before.IconID), strconv.Itoa(after.IconID), result.IconSrc[id]})
+		}
+	}
+	report.Conflicts = result.Conflicts
+	if report.Conflicts == nil {
+		report.Conflicts = []conflict{}
+	}
+	sort.Slice(report.New, func(i, j int) bool { return report.New[i].ID < report.New[j].ID })
+	sort.Slice(report.Names, func(i, j int) bool { return report.Names[i].ID < report.Names[j].ID })
+	sort.Slice(report.Icons, func(i, j int) bool { return report.Icons[i].ID < report.Icons[j].ID })
+	sort.SliceStable(report.Conflicts, func(i, j int) bool { return report.Conflicts[i].ID < report.Conflicts[j].ID })
+	return report
+}
+
+// writeDryRun will output the dry run report as text.
+func writeDryRun(w io.Writer, report dryRunReport) {
+	if len(report.Failed) > 0 {
+		fmt.Fprintf(w, "Failed files (%d):\n", len(report.Failed))
+		for _, f := range report.Failed {
+			fmt.Fprintf(w, "    %s - %s\n", f.Fname, f.Err)
+		}
+	}
+	if len(report.New)+len(report.Names)+len(report.Icons)+len(report.Conflicts) == 0 {
+		fmt.Fprintln(w, "No changes.")
+		return
+	}
+	if len(report.New) > 0 {
+		fmt.Fprintf(w, "New items (%d):\n", len(report.New))
+		for _, item := range report.New {
+			fmt.Fprintf(w, "    %d - %s - from %s\n", item.ID, item.Name, item.Source)
+		}
+	}
+	if len(report.Names) > 0 {
+		fmt.Fprintf(w, "Name changes (%d):\n", len(report.Names))
+		for _, c := range report.Names {
+			fmt.Fprintf(w, "    %d - %s -> %s - from %s\n", c.ID, c.Old, c.New, c.Source)
+		}
+	}
+	if len(report.Icons) > 0 {
+		fmt.Fprintf(w, "Icon ID changes (%d):\n", len(report.Icons))
+		for _, c := range report.Icons {
+			fmt.Fprintf(w, "    %d - %s - %s -> %s - from %s\n", c.ID, c.Name, c.Old, c.New, c.Source)
+		}
+	}
+	if len(report.Conflicts) > 0 {
+		fmt.Fprintf(w, "Conflicts (%d):\n", len(report.Conflicts))
+		for _, c := range report.Conflicts {
+			var values []string
+			for _, v := range c.Values {
+				values = append(values, fmt.Sprintf("\"%s\" (%s)", v.Value, strings.Join(v.Sources, ", ")))
+			}
+			chosen := "not changed"
+			if c.Chosen != "" {
+				chosen = fmt.Sprintf("using \"%s\"", c.Chosen)
+			}
+			fmt.Fprintf(w, "    %d - %s - %s - %s\n", c.ID, c.Field, strings.Join(values, " / "), chosen)
+		}
+	}
+}
+
+// writeDryRunJSON will output the dry run report as indented JSON.
+func writeDryRunJSON(w io.Writer, report dryRunReport) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(report)
+}
@@ -6,9 +6,12 @@ package main
 
 import (
 	"flag"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
+	"time"
 
 	"github.com/nuttann/equtils/pkg/eqdb"
 	"github.com/nuttann/equtils/pkg/eqfile"
@@ -17,10 +20,13 @@ import (
 
 // 'config' holds locations of necessary data files.
 type config struct {
-	ItemDBLoc   string   // DB info (currently file name)
-	RealEstate  []string // All Real Estate Files to be read
-	Inventories []string // All Inventory Files to be read
-	LootFilters []string // All Loot Filter Files to be read
+	ItemDBLoc    string   // DB info (currently file name)
+	RealEstate   []string // All Real Estate Files to be read
+	Inventories  []string // All Inventory Files to be read
+	LootFilters  []string // All Loot Filter Files to be read
+	Policy       string   // How conflicts are resolved (priority, newest, manual)
+	Priority     []string // Source types, highest priority first
+	ConflictsOut string   // Conflicts list for manual resolution (Optional)
 }
 
 func readConfig(confFile string) (confData config) {
@@ -33,11 +39,168 @@ func readConfig(confFile string) (confData config) {
 	if err != nil {
 		log.Fatalf("error: Configuration file - %v", err)
 	}
+	if err = checkPolicy(confData); err != nil {
+		log.Fatalf("error: Configuration file - %v", err)
+	}
 	return
 }
 
+// sourceItem is the item data found in a line of a source file.
+type sourceItem struct {
+	ID      int
+	Name    string
+	IconID  int
+	HasIcon bool // Icon ID is known (Only loot filters have icon IDs)
+	Weak    bool // Name is only used for items without a name
+}
+
+// source is a configured file, its type, and the function that reads it.
+type source struct {
+	Fname string
+	Kind  string // Source type used for priority
+	Read  func(fname string) ([]sourceItem, error)
+}
+
+// readRE will return the item names in a real-estate file.
+func readRE(fname string) ([]sourceItem, error) {
+	reData, err := eqfile.ReadRE(fname)
+	if err != nil {
+		return nil, fmt.Errorf("reading house file - %v", err)
+	}
+	var items []sourceItem
+	for _, entry := range reData {
+		items = append(items, sourceItem{ID: entry.ID, Name: entry.ItemName})
+	}
+	return items, nil
+}
+
+// readInventory will return the item names in an inventory file.
+func readInventory(fname string) ([]sourceItem, error) {
+	invData, err := eqfile.ReadInventory(fname)
+	if err != nil {
+		return nil, fmt.Errorf("reading inventory file - %v", err)
+	}
+	var items []sourceItem
+	for _, entry := range invData {
+		items = append(items, sourceItem{ID: entry.ID, Name: entry.Name})
+	}
+	return items, nil
+}
+
+// readLF will return the item icon IDs and names in a loot filter file.
+// Loot filter names may be old, so they are only used for items without a
+// name.
+func readLF(fname string) ([]sourceItem, error) {
+	lfData, err := eqfile.ReadLF(fname)
+	if err != nil {
+		return nil, fmt.Errorf("reading lootfilter file - %v", err)
+	}
+	var items []sourceItem
+	for _, entry := range lfData {
+		items = append(items, sourceItem{
+			ID:      entry.ID,
+			Name:    entry.Name,
+			IconID:  entry.IconID,
+			HasIcon: true,
+			Weak:    true,
+		})
+	}
+	return items, nil
+}
+
+// sources will return all configured files. The order does not matter since
+// all files are read before any values are picked.
+func sources(conf config) []source {
+	var srcs []source
+	for _, fname := range conf.RealEstate {
+		srcs = append(srcs, source{fname, kindRE, readRE})
+	}
+	for _, fname := range conf.Inventories {
+		srcs = append(srcs, source{fname, kindInventory, readInventory})
+	}
+	for _, fname := range conf.LootFilters {
+		srcs = append(srcs, source{fname, kindLF, readLF})
+	}
+	return srcs
+}
+
+// run will update the item DB from all the sources, then report and save the
+// conflicts. Only the files not in 'files' are read. It returns false if any
+// file could not be read or the conflicts could not be saved.
+func run(conf config, itemDB *eqdb.Items, srcs []source, files map[string]fileData) bool {
+	result := update(itemDB, conf, srcs, files)
+	ok := len(result.Failed) == 0
+	for _, f := range result.Failed {
+		fmt.Printf("error: %s - %s\n", f.Fname, f.Err)
+	}
+	if len(result.Conflicts) > 0 {
+		fmt.Printf("%d conflicts between files.", len(result.Conflicts))
+		if conf.ConflictsOut != "" {
+			fmt.Printf(" See %s.", conf.ConflictsOut)
+		}
+		fmt.Println()
+	}
+	if conf.ConflictsOut != "" {
+		if err := writeConflicts(conf.ConflictsOut, result.Conflicts); err != nil {
+			fmt.Printf("error: Writing conflicts file - %v\n", err)
+			ok = false
+		}
+	}
+	writeSummary(os.Stdout, result)
+	return ok
+}
+
+// writeSummary will output which files were read and which failed. The
+// errors are printed as they are found, so only the names are listed. Only
+// the count is given when nothing failed.
+func writeSummary(w io.Writer, result updateResult) {
+	if len(result.Failed) == 0 {
+		fmt.Fprintf(w, "Read %d files.\n", len(result.Read))
+		return
+	}
+	fmt.Fprintf(w, "Read %d files:\n", len(result.Read))
+	for _, fname := range result.Read {
+		fmt.Fprintf(w, "    %s\n", fname)
+	}
+	fmt.Fprintf(w, "Failed %d files:\n", len(result.Failed))
+	for _, f := range result.Failed {
+		fmt.Fprintf(w, "    %s\n", f.Fname)
+	}
+}
+
+// watch will poll all configured files and update the item DB whenever any of
+// them change. Only the changed files are read again. What was read from the
+// other files is kept, so the values and conflicts are picked from all files
+// the same way as in the first pass. This never returns.
+func watch(conf config, itemDB *eqdb.Items, files map[string]fileData, interval time.Duration) {
+	srcs := sources(conf)
+	var fnames []string
+	for _, src := range srcs {
+		fnames = append(fnames, src.Fname)
+	}
+	watcher := eqfile.NewWatcher(fnames)
+	fmt.Println("Watching", len(fnames), "files for changes.")
+	for {
+		time.Sleep(interval)
+		changed := watcher.Changed()
+		if len(changed) == 0 {
+			continue
+		}
+		for _, fname := range changed {
+			fmt.Println("Changed -", fname)
+			delete(files, fname) // Read again.
+		}
+		run(conf, itemDB, srcs, files) // Errors are printed. Keep watching.
+		itemDB.Close()                 // Updates if anything was changed.
+	}
+}
+
 func main() {
 	confPtr := flag.String("conf", "", "Configuration file. (Required)")
+	watchPtr := flag.Bool("watch", false, "Keep running and update the DB when files change.")
+	intervalPtr := flag.Duration("interval", 5*time.Second, "How often to check files in watch mode.")
+	dryRunPtr := flag.Bool("dryrun", false, "Only report what would change. Nothing is written.")
+	jsonPtr := flag.Bool("json", false, "Write the dry run report as JSON.")
 	flag.Parse()
 
 	if *confPtr == "" {
@@ -47,40 +210,37 @@ func main() {
 	// Get file paths to necessary data files.
 	conf := readConfig(*confPtr)
 
-	itemDB := eqdb.OpenItemDB(conf.ItemDBLoc)
-	defer itemDB.Close()
+	if *dryRunPtr && *watchPtr {
+		log.Fatalf("error: -dryrun cannot be used with -watch")
+	}
+	if *jsonPtr && !*dryRunPtr {
+		log.Fatalf("error: -json needs -dryrun")
+	}
 
-	for _, re := range conf.RealEstate {
-		reData, err := eqfile.ReadRE(re)
-		if err != nil {
-			log.Fatalf("error: Reading house file - %v", err)
+	itemDB := eqdb.OpenItemDB(conf.ItemDBLoc)
+	if *dryRunPtr {
+		report := dryRun(&itemDB, conf, sources(conf))
+		if *jsonPtr {
+			if err := writeDryRunJSON(os.Stdout, report); err != nil {
+				log.Fatalf("error: Writing report - %v", err)
+			}
+		} else {
+			writeDryRun(os.Stdout, report)
 		}
-		for _, entry := range reData {
-			itemDB.SetName(entry.ID, entry.ItemName)
+		if len(report.Failed) > 0 {
+			os.Exit(1)
 		}
+		return
 	}
-	for _, i := range conf.Inventories {
-		// Process Inventory File.
-		invData, err := eqfile.ReadInventory(i)
-		if err != nil {
-			log.Fatalf("error: Reading inventory file - %v", err)
-		}
-		for _, entry := range invData {
-			itemDB.SetName(entry.ID, entry.Name)
-		}
+
+	// Updates from the files that were read are saved even if some failed.
+	files := make(map[string]fileData)
+	ok := run(conf, &itemDB, sources(conf), files)
+	itemDB.Close()
+	if *watchPtr {
+		watch(conf, &itemDB, files, *intervalPtr)
 	}
-	for _, lf := range conf.LootFilters {
-		// Process LootFilters.
-		lfData, err := eqfile.ReadLF(lf)
-		if err != nil {
-			log.Fatalf("error: Reading lootfilter file - %v", err)
-		}
-		for _, entry := range lfData {
-			// Loot filter names may be old. Only add new names.
-			if itemDB.Name(entry.ID) == "" {
-				itemDB.SetName(entry.ID, entry.Name) // Use this if name is not known.
-			}
-			itemDB.SetIconID(entry.ID, entry.IconID)
-		}
+	if !ok {
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,314 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/nuttann/equtils/pkg/eqdb"
+	"github.com/nuttann/equtils/pkg/eqfile"
+	"gopkg.in/yaml.v2"
+)
+
+// All files are read before the item DB is updated. When files disagree about
+// the name or icon ID of an item, the value is picked by the configured
+// policy rather than by the order the files are read:
+//   - "priority" - The highest priority source type wins, then the newest
+//     file. (Default)
+//   - "newest" - The newest file wins, then the highest priority source type.
+//   - "manual" - The DB is not changed. The disagreement is only listed.
+//
+// Every disagreement is listed as a conflict. Loot filter names are only used
+// for items without any other name, so they are never in conflict.
+
+// Source types used in the priority list.
+const (
+	kindRE        = "realestate"
+	kindInventory = "inventories"
+	kindLF        = "lootfilters"
+)
+
+// defaultPriority is the source type priority if none is configured, highest
+// first.
+var defaultPriority = []string{kindInventory, kindRE, kindLF}
+
+// Conflict policies.
+const (
+	policyPriority = "priority"
+	policyNewest   = "newest"
+	policyManual   = "manual"
+)
+
+// conflict is an item field that the sources disagree about.
+type conflict struct {
+	ID     int             `json:"id"`
+	Field  string          `json:"field"`  // "name" or "iconid"
+	Chosen string          `json:"chosen"` // Value used ("" if not changed)
+	Values []conflictValue `json:"values"`
+}
+
+// conflictValue is a value given by some of the sources.
+type conflictValue struct {
+	Value   string   `json:"value"`
+	Sources []string `json:"sources"`
+}
+
+// candidate is a value for an item field given by a source file.
+type candidate struct {
+	Value   string
+	Source  string
+	Rank    int       // Position of the source type in the priority list
+	ModTime time.Time // Modification time of the source file
+}
+
+// fileData is what was read from a source file. The files read are kept in
+// watch mode so that only the files that changed are read again.
+type fileData struct {
+	Items   []sourceItem
+	ModTime time.Time
+	Err     error // Set if the file could not be read
+}
+
+// readSource will read the source file.
+func readSource(src source) fileData {
+	info, err := os.Stat(src.Fname)
+	if err != nil {
+		return fileData{Err: err}
+	}
+	items, err := src.Read(src.Fname)
+	return fileData{Items: items, ModTime: info.ModTime(), Err: err}
+}
+
+// fileError is a file that could not be read.
+type fileError struct {
+	Fname string `json:"file"`
+	Err   string `json:"error"`
+}
+
+// updateResult holds where the values set in the item DB came from, the
+// conflicts found, and which files were read.
+type updateResult struct {
+	NameSrc   map[int]string // File that set each name
+	IconSrc   map[int]string // File that set each icon ID
+	Conflicts []conflict
+	Read      []string    // Files read
+	Failed    []fileError // Files that could not be read
+}
+
+// checkPolicy will return an error if the policy or priority list in the
+// configuration is not valid.
+func checkPolicy(conf config) error {
+	switch conf.Policy {
+	case "", policyPriority, policyNewest, policyManual:
+	default:
+		return fmt.Errorf("unknown policy \"%s\"", conf.Policy)
+	}
+	seen := make(map[string]bool)
+	for _, kind := range conf.Priority {
+		if !contains(defaultPriority, kind) {
+			return fmt.Errorf("unknown source type \"%s\" in priority", kind)
+		}
+		if seen[kind] {
+			return fmt.Errorf("source type \"%s\" is in priority more than once", kind)
+		}
+		seen[kind] = true
+	}
+	return nil
+}
+
+// rank will return the position of the source type in the configured priority
+// list. Types not listed come after the listed ones in the default order.
+func rank(conf config, kind string) int {
+	for n, k := range conf.Priority {
+		if k == kind {
+			return n
+		}
+	}
+	for n, k := range defaultPriority {
+		if k == kind {
+			return len(conf.Priority) + n
+		}
+	}
+	return len(conf.Priority) + len(defaultPriority)
+}
+
+// contains returns true if the string is in the list.
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// candidates collects the candidate values for a field of each item.
+type candidates map[int][]candidate
+
+// add will record the candidate for the item. A file only gives a value once.
+func (c candidates) add(id int, cand candidate) {
+	for _, existing := range c[id] {
+		if existing.Value == cand.Value && existing.Source == cand.Source {
+			return
+		}
+	}
+	c[id] = append(c[id], cand)
+}
+
+// ids will return the item IDs sorted.
+func (c candidates) ids() []int {
+	var ids []int
+	for id := range c {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// pick will return the winning candidate by the policy and whether all the
+// candidates agree.
+func pick(policy string, cands []candidate) (candidate, bool) {
+	sorted := append([]candidate(nil), cands...)
+	byRank := func(i, j int) bool { return sorted[i].Rank < sorted[j].Rank }
+	byTime := func(i, j int) bool { return sorted[i].ModTime.After(sorted[j].ModTime) }
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if policy == policyNewest {
+			if !sorted[i].ModTime.Equal(sorted[j].ModTime) {
+				return byTime(i, j)
+			}
+			return byRank(i, j)
+		}
+		if sorted[i].Rank != sorted[j].Rank {
+			return byRank(i, j)
+		}
+		return byTime(i, j)
+	})
+	agree := true
+	for _, cand := range sorted {
+		agree = agree && cand.Value == sorted[0].Value
+	}
+	return sorted[0], agree
+}
+
+// newConflict will return the conflict for the candidates grouped by value.
+func newConflict(id int, field string, chosen string, cands []candidate) conflict {
+	c := conflict{ID: id, Field: field, Chosen: chosen}
+	for _, cand := range cands {
+		found := false
+		for n := range c.Values {
+			if c.Values[n].Value == cand.Value {
+				c.Values[n].Sources = append(c.Values[n].Sources, cand.Source)
+				found = true
+			}
+		}
+		if !found {
+			c.Values = append(c.Values, conflictValue{cand.Value, []string{cand.Source}})
+		}
+	}
+	sort.Slice(c.Values, func(i, j int) bool { return c.Values[i].Value < c.Values[j].Value })
+	return c
+}
+
+// update will update the item DB with the values picked by the configured
+// policy from all the source files. Files not in 'files' are read and added to
+// it, so passing the same map again only reads files that were removed from it.
+// A file that cannot be read is recorded and skipped so the other files are
+// still used.
+func update(itemDB *eqdb.Items, conf config, srcs []source, files map[string]fileData) updateResult {
+	result := updateResult{
+		NameSrc: make(map[int]string),
+		IconSrc: make(map[int]string),
+	}
+	names := make(candidates)
+	weakNames := make(candidates) // Only used for items without a name
+	icons := make(candidates)
+	for _, src := range srcs {
+		data, ok := files[src.Fname]
+		if !ok {
+			data = readSource(src)
+			files[src.Fname] = data
+		}
+		if data.Err != nil {
+			result.Failed = append(result.Failed, fileError{src.Fname, data.Err.Error()})
+			continue
+		}
+		result.Read = append(result.Read, src.Fname)
+		r := rank(conf, src.Kind)
+		for _, item := range data.Items {
+			cand := candidate{Value: item.Name, Source: src.Fname, Rank: r, ModTime: data.ModTime}
+			if item.Weak {
+				weakNames.add(item.ID, cand)
+			} else {
+				names.add(item.ID, cand)
+			}
+			if item.HasIcon {
+				cand.Value = strconv.Itoa(item.IconID)
+				icons.add(item.ID, cand)
+			}
+		}
+	}
+
+	// set will pick the value for each item and set it in the DB.
+	set := func(cands candidates, field string, setValue func(id int, value string) bool, srcMap map[int]string) {
+		for _, id := range cands.ids() {
+			winner, agree := pick(conf.Policy, cands[id])
+			if !agree {
+				chosen := winner.Value
+				if conf.Policy == policyManual {
+					chosen = ""
+				}
+				result.Conflicts = append(result.Conflicts, newConflict(id, field, chosen, cands[id]))
+				if conf.Policy == policyManual {
+					continue
+				}
+			}
+			if setValue(id, winner.Value) {
+				srcMap[id] = winner.Source
+			}
+		}
+	}
+	set(names, "name", func(id int, value string) bool {
+		old := itemDB.Name(id)
+		itemDB.SetName(id, value)
+		return old != value
+	}, result.NameSrc)
+	for _, id := range weakNames.ids() {
+		if _, ok := names[id]; ok || itemDB.Name(id) != "" {
+			continue
+		}
+		winner, _ := pick(policyNewest, weakNames[id]) // Newest is least likely to be old.
+		itemDB.SetName(id, winner.Value)
+		result.NameSrc[id] = winner.Source
+	}
+	set(icons, "iconid", func(id int, value string) bool {
+		iconID, _ := strconv.Atoi(value)
+		old := itemDB.IconID(id)
+		itemDB.SetIconID(id, iconID)
+		return old != iconID
+	}, result.IconSrc)
+	return result
+}
+
+// writeConflicts will save the conflicts as a YAML file for manual
+// resolution. The file is replaced on each run. The previous file is left in
+// place if it cannot be written.
+func writeConflicts(fname string, conflicts []conflict) error {
+	if conflicts == nil {
+		conflicts = []conflict{}
+	}
+	dat, err := yaml.Marshal(conflicts)
+	if err != nil {
+		return err
+	}
+	return eqfile.ReplaceFile(fname, func(w *bufio.Writer) error {
+		_, err := w.Write(dat)
+		return err
+	})
+}
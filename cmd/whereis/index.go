@@ -0,0 +1,47 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/nuttann/equtils/pkg/eqdb"
+)
+
+// areas are the areas that can be searched.
+var areas = []string{eqdb.AreaWorn, eqdb.AreaBags, eqdb.AreaBank, eqdb.AreaSharedBank,
+	eqdb.AreaHouse, eqdb.AreaYard}
+
+// addDumps will add the dump files of the character to the index. A dump file
+// that no longer exists is not an error if the index already holds a
+// snapshot of it. Problems are printed and false is returned if any file
+// could not be added.
+func addDumps(index *eqdb.InventoryIndex, c character) bool {
+	ok := true
+	for _, dump := range []struct {
+		Kind, Fname string
+		Add         func(character string, server string, fname string) (bool, error)
+	}{
+		{eqdb.InvKindInventory, c.Inventory, index.AddInventoryFile},
+		{eqdb.InvKindRealEstate, c.RealEstate, index.AddRealEstateFile},
+	} {
+		if dump.Fname == "" {
+			continue
+		}
+		read, err := dump.Add(c.Name, c.Server, dump.Fname)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s - %v\n", c.Name, err)
+			ok = false
+			continue
+		}
+		if !read {
+			snap, _ := index.Snapshot(c.Name, c.Server, dump.Kind)
+			fmt.Fprintf(os.Stderr, "note: %s - %s not found - using the %s from %s\n",
+				c.Name, dump.Fname, dump.Kind, snap.Time.Format("2006-01-02 15:04"))
+		}
+	}
+	return ok
+}
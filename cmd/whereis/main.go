@@ -0,0 +1,246 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nuttann/equtils/pkg/eqdb"
+	"gopkg.in/yaml.v2"
+)
+
+// 'config' holds the item DB location and the dump files of each character.
+type config struct {
+	ItemDBLoc  string // DB location info (currently file name) (Optional)
+	IndexLoc   string // Inventory index location (currently file name) (Optional)
+	Characters []character
+}
+
+// character holds the dump files of a character.
+type character struct {
+	Name       string
+	Server     string // (Required if there is an index)
+	Inventory  string // "/output inventory" file (Optional)
+	RealEstate string // "/output realestate" file (Optional)
+}
+
+func readConfig(confFile string) (confData config) {
+	// Read configuration data.
+	buf, err := ioutil.ReadFile(confFile)
+	if err != nil {
+		log.Fatalf("error: Configuration file - %v", err)
+	}
+	err = yaml.Unmarshal(buf, &confData)
+	if err != nil {
+		log.Fatalf("error: Configuration file - %v", err)
+	}
+	if len(confData.Characters) == 0 {
+		log.Fatalf("error: Configuration file - no characters given")
+	}
+	for n, c := range confData.Characters {
+		if c.Name == "" {
+			log.Fatalf("error: Configuration file - characters[%d] has no name", n+1)
+		}
+		// The index keys on the server, so a character without one would be
+		// held twice if another program gave its server.
+		if confData.IndexLoc != "" && c.Server == "" {
+			log.Fatalf("error: Configuration file - characters[%d] (%s) needs a server when indexloc is given", n+1, c.Name)
+		}
+	}
+	return
+}
+
+// holding is a stack of an item held by a character.
+type holding struct {
+	Character string    `json:"character"`
+	Server    string    `json:"server,omitempty"`
+	Area      string    `json:"area"`     // E.g., "bags", "bank", "shared bank", or "house"
+	Location  string    `json:"location"` // Slot or real estate address
+	Count     int       `json:"count"`
+	Time      time.Time `json:"time"` // When the dump was written
+}
+
+// foundItem is an item matching a search and where it is.
+type foundItem struct {
+	ID       int       `json:"id"`
+	Name     string    `json:"name"`
+	Total    int       `json:"total"`
+	Holdings []holding `json:"holdings"`
+}
+
+// search is the items found for a search text.
+type search struct {
+	Text  string      `json:"search"`
+	Items []foundItem `json:"items"`
+}
+
+// findIDs will return the IDs of the items for the text. The text is either an
+// item ID, an item name (ignoring case), or words to match in item names.
+func findIDs(itemDB *eqdb.Items, text string) []int {
+	if id, err := strconv.Atoi(text); err == nil {
+		return []int{id}
+	}
+	if ids := itemDB.FindName(text); len(ids) > 0 {
+		return ids
+	}
+	return itemDB.Match(text)
+}
+
+// find will return where the items for the text are. Only items held by a
+// character are returned. All items matching the rest of the query are
+// returned if the text is empty.
+func find(itemDB *eqdb.Items, index *eqdb.InventoryIndex, query eqdb.InvQuery, text string) search {
+	result := search{Text: text, Items: []foundItem{}}
+	if text != "" {
+		query.IDs = findIDs(itemDB, text)
+		if len(query.IDs) == 0 {
+			return result
+		}
+	}
+	byID := make(map[int]*foundItem)
+	var ids []int
+	for _, h := range index.Find(query) {
+		item, ok := byID[h.ID]
+		if !ok {
+			item = &foundItem{ID: h.ID, Name: itemDB.Name(h.ID), Holdings: []holding{}}
+			byID[h.ID] = item
+			ids = append(ids, h.ID)
+		}
+		item.Holdings = append(item.Holdings, holding{h.Character, h.Server, h.Area, h.Location, h.Count, h.Time})
+		item.Total += h.Count
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := byID[ids[i]], byID[ids[j]]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ID < b.ID
+	})
+	for _, id := range ids {
+		result.Items = append(result.Items, *byID[id])
+	}
+	return result
+}
+
+// writeText will output each search with where the items found are.
+func writeText(w io.Writer, searches []search) {
+	for _, s := range searches {
+		if len(s.Items) == 0 {
+			if s.Text == "" {
+				fmt.Fprintln(w, "==== Nothing found")
+			} else {
+				fmt.Fprintf(w, "==== \"%s\" - not found\n", s.Text)
+			}
+			continue
+		}
+		for _, item := range s.Items {
+			fmt.Fprintf(w, "==== %s (%d) - %d held\n", item.Name, item.ID, item.Total)
+			for _, h := range item.Holdings {
+				who := h.Character
+				if h.Server != "" {
+					who += " (" + h.Server + ")"
+				}
+				fmt.Fprintf(w, "    %s - %s - %s - %d - as of %s\n", who, h.Area, h.Location, h.Count,
+					h.Time.Format("2006-01-02"))
+			}
+		}
+	}
+}
+
+func main() {
+	confPtr := flag.String("conf", "", "Configuration file. (Required)")
+	jsonPtr := flag.Bool("json", false, "Write the results as JSON.")
+	toonPtr := flag.String("toon", "", "Only find items held by this character.")
+	serverPtr := flag.String("server", "", "Only find items on this server.")
+	areaPtr := flag.String("area", "", "Only find items in this area: "+strings.Join(areas, ", ")+".")
+	locPtr := flag.String("location", "", "Only find items whose location contains this text.")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(),
+			"Usage: %s -conf FILE [-json] [-toon NAME] [-server NAME] [-area AREA] [-location TEXT] [ITEM...]\n",
+			os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	query := eqdb.InvQuery{Character: *toonPtr, Server: *serverPtr, Area: *areaPtr, Location: *locPtr}
+	filtered := *toonPtr != "" || *serverPtr != "" || *areaPtr != "" || *locPtr != ""
+	if *confPtr == "" || (flag.NArg() == 0 && !filtered) {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if query.Area != "" && !contains(areas, query.Area) {
+		log.Fatalf("error: -area must be one of %s", strings.Join(areas, ", "))
+	}
+	conf := readConfig(*confPtr)
+	itemDB := eqdb.Items{DB: make(map[int]eqdb.Item)}
+	if conf.ItemDBLoc != "" {
+		itemDB = eqdb.OpenItemDB(conf.ItemDBLoc)
+	}
+	index := eqdb.InventoryIndex{}
+	if conf.IndexLoc != "" {
+		var err error
+		if index, err = eqdb.OpenInventoryIndex(conf.IndexLoc); err != nil {
+			log.Fatalf("error: Inventory index file - %v", err)
+		}
+	}
+
+	// Errors are printed to stderr so they do not mix with the JSON output.
+	failed := false
+	for _, c := range conf.Characters {
+		if !addDumps(&index, c) {
+			failed = true
+		}
+	}
+	if conf.IndexLoc != "" {
+		if err := index.Close(); err != nil {
+			log.Fatalf("error: Inventory index file - %v", err)
+		}
+	}
+	// Names in the dumps find items not yet in the item DB. It is not saved.
+	for _, h := range index.Find(eqdb.InvQuery{}) {
+		itemDB.SetName(h.ID, h.Name)
+	}
+
+	var searches []search
+	if flag.NArg() == 0 {
+		searches = append(searches, find(&itemDB, &index, query, ""))
+	}
+	for _, text := range flag.Args() {
+		searches = append(searches, find(&itemDB, &index, query, text))
+	}
+	if *jsonPtr {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(searches); err != nil {
+			log.Fatalf("error: Writing results - %v", err)
+		}
+	} else {
+		writeText(os.Stdout, searches)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// contains returns true if the string is in the list.
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
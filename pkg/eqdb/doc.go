@@ -12,5 +12,51 @@ Items
 
 This holds item attributes extracted from various sources. Currently that is
 very limited.
+
+Quests
+
+This holds the collection quest data (Expansions -> Zones -> Quests) read from
+the quest data file. The line of each entry in the file is kept so that
+problems found by ValidateQuests can be reported with their location.
+
+Ledger
+
+This holds reservations and hand-outs of items such as collection items that
+are given away. Each entry has the item, count, recipient, and dates. A
+reservation is pending until it is handed out.
+
+Stock history
+
+This holds snapshots of the count of each item stored in each house. A
+snapshot is only added when the counts change. The changes between snapshots
+and the history of a single item can be found from it.
+
+Progress
+
+This holds the collection items and quests completed by each character. Items
+of a completed quest are all counted as collected.
+
+Loot history
+
+This holds the items looted by each character with the time, zone, and corpse
+as found in chat logs. The same loot is seen in the log of each character
+present, so the records of a log are only added if the history does not
+already hold as many records for the character, item, count, and time.
+
+Guild roster
+
+This holds snapshots of the guild roster. A snapshot is only added when the
+members differ from the snapshot before it. The members that joined or left
+between snapshots can be found from it.
+
+Inventory index
+
+This holds the last inventory and real estate dump of each character with the
+time it was written. A dump only replaces the one held if it is newer. Items
+can be found by item, character, area (E.g., bank or house), location, and
+server, even after the dump files are deleted. Real estate dumps of characters
+on the same account list the same houses, so each stack in a house is only
+found once. Snapshots are added from the dump files with AddInventoryFile
+and AddRealEstateFile.
 */
 package eqdb
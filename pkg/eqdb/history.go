@@ -0,0 +1,143 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package eqdb
+
+import (
+	"sort"
+	"time"
+)
+
+// StockSnapshot holds the count of each item stored in each house at a time.
+type StockSnapshot struct {
+	Time   time.Time
+	Houses map[string]map[int]int // Counts by house address and item ID
+}
+
+// StockChange is a change in the count of an item in a house between two
+// snapshots. Before is 0 for added items and After is 0 for removed items.
+type StockChange struct {
+	Address string
+	ID      int
+	Before  int
+	After   int
+}
+
+// ItemCounts holds the counts of an item in each house at a time.
+type ItemCounts struct {
+	Time   time.Time
+	Counts map[string]int // Counts by house address
+}
+
+// StockHistory is the list of stock snapshots in the order taken.
+type StockHistory struct {
+	Snapshots []StockSnapshot // All snapshots, oldest first
+	Fname     string          // File to hold history
+	Changed   bool            // Set to true if history is altered and should be saved.
+}
+
+// OpenStockHistory will return the stock history read in from a YAML file. A
+// missing file results in an empty history that will be created when saved.
+func OpenStockHistory(fname string) (StockHistory, error) {
+	h := StockHistory{Fname: fname}
+	_, err := readYAMLFile(fname, &h.Snapshots)
+	return h, err
+}
+
+// Close will save the history to its YAML file if it changed.
+func (h *StockHistory) Close() error {
+	if !h.Changed {
+		return nil
+	}
+	err := writeYAMLFile(h.Fname, h.Snapshots)
+	if err == nil {
+		h.Changed = false
+	}
+	return err
+}
+
+// Add will add the snapshot to the history unless the counts are the same as
+// the latest snapshot. It returns true if the snapshot was added.
+func (h *StockHistory) Add(snap StockSnapshot) bool {
+	if n := len(h.Snapshots); n > 0 && sameCounts(h.Snapshots[n-1].Houses, snap.Houses) {
+		return false
+	}
+	h.Snapshots = append(h.Snapshots, snap)
+	h.Changed = true
+	return true
+}
+
+// sameCounts returns true if the house counts are the same.
+func sameCounts(a, b map[string]map[int]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for address, aItems := range a {
+		bItems, ok := b[address]
+		if !ok || len(aItems) != len(bItems) {
+			return false
+		}
+		for id, count := range aItems {
+			if bItems[id] != count {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// DiffSnapshots will return the changes in counts between the snapshots. Only
+// houses in both snapshots are compared, so adding or removing a house does
+// not list all of its items. The changes are sorted by address and then by
+// item ID.
+func DiffSnapshots(before, after StockSnapshot) []StockChange {
+	var changes []StockChange
+	for address, afterItems := range after.Houses {
+		beforeItems, ok := before.Houses[address]
+		if !ok {
+			continue
+		}
+		for id, count := range afterItems {
+			if beforeItems[id] != count {
+				changes = append(changes, StockChange{address, id, beforeItems[id], count})
+			}
+		}
+		for id, count := range beforeItems {
+			if _, ok := afterItems[id]; !ok {
+				changes = append(changes, StockChange{address, id, count, 0})
+			}
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Address != changes[j].Address {
+			return changes[i].Address < changes[j].Address
+		}
+		return changes[i].ID < changes[j].ID
+	})
+	return changes
+}
+
+// ItemHistory will return the counts of the item at each snapshot where they
+// changed, starting from the first snapshot that has the item.
+func (h *StockHistory) ItemHistory(id int) []ItemCounts {
+	var history []ItemCounts
+	last := make(map[string]int)
+	for _, snap := range h.Snapshots {
+		counts := make(map[string]int)
+		for address, items := range snap.Houses {
+			if count, ok := items[id]; ok {
+				counts[address] = count
+			}
+		}
+		same := len(counts) == len(last)
+		for address, count := range counts {
+			same = same && last[address] == count
+		}
+		if !same {
+			history = append(history, ItemCounts{Time: snap.Time, Counts: counts})
+			last = counts
+		}
+	}
+	return history
+}
@@ -0,0 +1,309 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package eqdb
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nuttann/equtils/pkg/eqfile"
+)
+
+// Kinds of inventory snapshots.
+const (
+	InvKindInventory  = "inventory"  // From an "/output inventory" dump
+	InvKindRealEstate = "realestate" // From an "/output realestate" dump
+)
+
+// Areas of inventory entries.
+const (
+	AreaWorn       = "worn"
+	AreaBags       = "bags"
+	AreaBank       = "bank"
+	AreaSharedBank = "shared bank"
+	AreaHouse      = "house"
+	AreaYard       = "yard"
+)
+
+// InvArea will return the area of an inventory dump location (E.g.,
+// "SharedBank1" is in the shared bank).
+func InvArea(loc string) string {
+	switch {
+	case strings.HasPrefix(loc, "SharedBank"):
+		return AreaSharedBank
+	case strings.HasPrefix(loc, "Bank"):
+		return AreaBank
+	case strings.HasPrefix(loc, "General"):
+		return AreaBags
+	}
+	return AreaWorn
+}
+
+// InvEntry is a stack of an item in an inventory snapshot.
+type InvEntry struct {
+	ID       int
+	Name     string
+	Area     string // One of the Area constants
+	Location string // Inventory slot or real estate address
+	Count    int
+	Owner    string `yaml:",omitempty"` // Owner of a real estate item if not the character
+}
+
+// InvSnapshot is the last dump of one kind for a character.
+type InvSnapshot struct {
+	Character string
+	Server    string    `yaml:",omitempty"`
+	Kind      string    // One of the InvKind constants
+	Time      time.Time // When the dump was written
+	Entries   []InvEntry
+}
+
+// InvQuery selects inventory entries. Empty fields match everything.
+type InvQuery struct {
+	IDs       []int  // Item IDs
+	Character string // Character holding the item (ignoring case)
+	Server    string // Server (ignoring case)
+	Area      string // One of the Area constants
+	Location  string // Text in the location (ignoring case)
+}
+
+// InvHolding is an inventory entry found by a query along with the snapshot
+// holding it. The character is the owner for real estate items owned by
+// another character.
+type InvHolding struct {
+	InvEntry
+	Character string
+	Server    string
+	Time      time.Time // When the dump was written
+}
+
+// InventoryIndex holds the last inventory and real estate snapshot of each
+// character. It keeps the items of a character even after the dump files are
+// deleted.
+type InventoryIndex struct {
+	Snapshots []InvSnapshot // Sorted by character, server, and kind
+	Fname     string        // File to hold index
+	Changed   bool          // Set to true if index is altered and should be saved.
+}
+
+// OpenInventoryIndex will return the index read in from a YAML file. A
+// missing file results in an empty index that will be created when saved.
+func OpenInventoryIndex(fname string) (InventoryIndex, error) {
+	x := InventoryIndex{Fname: fname}
+	_, err := readYAMLFile(fname, &x.Snapshots)
+	return x, err
+}
+
+// Close will save the index to its YAML file if it changed.
+func (x *InventoryIndex) Close() error {
+	if !x.Changed {
+		return nil
+	}
+	err := writeYAMLFile(x.Fname, x.Snapshots)
+	if err == nil {
+		x.Changed = false
+	}
+	return err
+}
+
+// sameSnapshot returns true if the snapshots are for the same character,
+// server, and kind.
+func sameSnapshot(a, b InvSnapshot) bool {
+	return strings.EqualFold(a.Character, b.Character) &&
+		strings.EqualFold(a.Server, b.Server) && a.Kind == b.Kind
+}
+
+// Set will replace the snapshot of the character, server, and kind unless the
+// one held is as new or newer. It returns true if the index changed.
+func (x *InventoryIndex) Set(snap InvSnapshot) bool {
+	for n, old := range x.Snapshots {
+		if !sameSnapshot(old, snap) {
+			continue
+		}
+		if !old.Time.Before(snap.Time) {
+			return false
+		}
+		x.Snapshots[n] = snap
+		x.Changed = true
+		return true
+	}
+	x.Snapshots = append(x.Snapshots, snap)
+	sort.SliceStable(x.Snapshots, func(i, j int) bool {
+		a, b := x.Snapshots[i], x.Snapshots[j]
+		if !strings.EqualFold(a.Character, b.Character) {
+			return strings.ToLower(a.Character) < strings.ToLower(b.Character)
+		}
+		if !strings.EqualFold(a.Server, b.Server) {
+			return strings.ToLower(a.Server) < strings.ToLower(b.Server)
+		}
+		return a.Kind < b.Kind
+	})
+	x.Changed = true
+	return true
+}
+
+// Snapshot will return the snapshot of the character, server, and kind and
+// true, or false if there is none.
+func (x *InventoryIndex) Snapshot(character string, server string, kind string) (InvSnapshot, bool) {
+	want := InvSnapshot{Character: character, Server: server, Kind: kind}
+	for _, snap := range x.Snapshots {
+		if sameSnapshot(snap, want) {
+			return snap, true
+		}
+	}
+	return InvSnapshot{}, false
+}
+
+// AddInventoryFile will set the inventory snapshot of the character from an
+// "/output inventory" file. Empty slots are not kept. It returns false if the
+// file no longer exists and the snapshot already held is kept instead. See
+// Set for when the snapshot is replaced.
+//
+// Error reasons:
+//   - File cannot be read and there is no snapshot to keep.
+//   - File is not an inventory file.
+func (x *InventoryIndex) AddInventoryFile(character string, server string, fname string) (bool, error) {
+	return x.addFile(character, server, InvKindInventory, fname, func() ([]InvEntry, error) {
+		items, err := eqfile.ReadInventory(fname)
+		if err != nil {
+			return nil, err
+		}
+		var entries []InvEntry
+		for _, item := range items {
+			if item.ID == 0 {
+				continue // Empty slot
+			}
+			entries = append(entries, InvEntry{
+				ID:       item.ID,
+				Name:     item.Name,
+				Area:     InvArea(item.Loc),
+				Location: item.Loc,
+				Count:    item.Count,
+			})
+		}
+		return entries, nil
+	})
+}
+
+// AddRealEstateFile will set the real estate snapshot of the character from
+// an "/output realestate" file. The location of an entry is the real estate
+// name and status (E.g., "Village, 113 Vanward (Stored)"). Items owned by
+// another character of the account keep that owner. It returns false if the
+// file no longer exists and the snapshot already held is kept instead.
+//
+// Error reasons:
+//   - File cannot be read and there is no snapshot to keep.
+//   - File is not a real estate file.
+func (x *InventoryIndex) AddRealEstateFile(character string, server string, fname string) (bool, error) {
+	return x.addFile(character, server, InvKindRealEstate, fname, func() ([]InvEntry, error) {
+		items, err := eqfile.ReadRE(fname)
+		if err != nil {
+			return nil, err
+		}
+		var entries []InvEntry
+		for _, item := range items {
+			e := InvEntry{
+				ID:       item.ID,
+				Name:     item.ItemName,
+				Area:     AreaHouse,
+				Location: fmt.Sprintf("%s (%s)", item.REName, item.Status),
+				Count:    item.Count,
+			}
+			if item.RELoc == "Neighborhood" {
+				e.Area = AreaYard
+			}
+			if !strings.EqualFold(item.Owner, character) {
+				e.Owner = item.Owner
+			}
+			entries = append(entries, e)
+		}
+		return entries, nil
+	})
+}
+
+// addFile will set the snapshot of the kind from the entries read from the
+// file. The snapshot time is the time the file was written.
+func (x *InventoryIndex) addFile(character string, server string, kind string, fname string,
+	read func() ([]InvEntry, error)) (bool, error) {
+	info, err := os.Stat(fname)
+	if os.IsNotExist(err) {
+		if _, found := x.Snapshot(character, server, kind); found {
+			return false, nil
+		}
+	}
+	if err != nil {
+		return false, err
+	}
+	entries, err := read()
+	if err != nil {
+		return false, err
+	}
+	x.Set(InvSnapshot{
+		Character: character,
+		Server:    server,
+		Kind:      kind,
+		Time:      info.ModTime(),
+		Entries:   entries,
+	})
+	return true, nil
+}
+
+// Find will return the entries matching the query, sorted by character. The
+// same real estate may be in the snapshots of several characters of an
+// account, so an entry is only returned once for an owner, location, and
+// item.
+func (x *InventoryIndex) Find(q InvQuery) []InvHolding {
+	ids := make(map[int]bool)
+	for _, id := range q.IDs {
+		ids[id] = true
+	}
+	type reKey struct {
+		Owner, Server, Location string
+		ID                      int
+	}
+	seenRE := make(map[reKey]bool)
+	var found []InvHolding
+	for _, snap := range x.Snapshots {
+		if q.Server != "" && !strings.EqualFold(snap.Server, q.Server) {
+			continue
+		}
+		var reKeys []reKey // Real estate entries of this snapshot
+		for _, e := range snap.Entries {
+			h := InvHolding{InvEntry: e, Character: snap.Character, Server: snap.Server, Time: snap.Time}
+			if e.Owner != "" {
+				h.Character = e.Owner
+			}
+			switch {
+			case len(ids) > 0 && !ids[e.ID]:
+				continue
+			case q.Character != "" && !strings.EqualFold(h.Character, q.Character):
+				continue
+			case q.Area != "" && e.Area != q.Area:
+				continue
+			case q.Location != "" && !strings.Contains(strings.ToLower(e.Location), strings.ToLower(q.Location)):
+				continue
+			}
+			if snap.Kind == InvKindRealEstate {
+				key := reKey{strings.ToLower(h.Character), strings.ToLower(snap.Server), e.Location, e.ID}
+				if seenRE[key] {
+					continue
+				}
+				reKeys = append(reKeys, key)
+			}
+			found = append(found, h)
+		}
+		// Only mark after the snapshot so repeated stacks within it are kept.
+		for _, key := range reKeys {
+			seenRE[key] = true
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		return strings.ToLower(found[i].Character) < strings.ToLower(found[j].Character)
+	})
+	return found
+}
@@ -9,6 +9,9 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
 
 	"gopkg.in/yaml.v2"
 )
@@ -24,6 +27,9 @@ type Items struct {
 	DB      map[int]Item // Currently known items
 	Fname   string       // File to hold DB
 	Changed bool         // Set to true if DB is altered and should be saved.
+
+	names map[int]bool // IDs whose names were set since the DB was saved
+	icons map[int]bool // IDs whose icon IDs were set since the DB was saved
 }
 
 // OpenItemDB will return a DB read in from a YAML file.
@@ -31,7 +37,7 @@ func OpenItemDB(dbFile string) (i Items) {
 	i.Fname = dbFile
 	dat, err := ioutil.ReadFile(dbFile)
 	if err != nil {
-		fmt.Print("Cannot read DB file. Starting with empty item DB.")
+		fmt.Fprintln(os.Stderr, "Cannot read DB file. Starting with empty item DB.")
 		i.DB = make(map[int]Item)
 		return
 	}
@@ -39,9 +45,14 @@ func OpenItemDB(dbFile string) (i Items) {
 	return
 }
 
-// Close will save the item DB to a YAML file if the DB changed.
+// Close will save the item DB to a YAML file if the DB changed. The file is
+// read again first and only the names and icon IDs set by this program are
+// changed in it, so programs sharing the DB (E.g., "updateitemdb -watch" and
+// "collectstoweb -watch") do not undo each other's updates. After saving, DB
+// also holds the updates made by the other programs.
 func (i *Items) Close() {
 	if i.Changed {
+		i.merge()
 		fmt.Println("New data - Updating DB file.")
 		fmt.Printf("    Orig DB file name - %s\n", i.Fname)
 		fmt.Printf("    DB directory %s\n", filepath.Dir(i.Fname))
@@ -71,17 +82,165 @@ func (i *Items) Close() {
 		err = os.Rename(f.Name(), i.Fname)
 		if err != nil {
 			fmt.Printf("Error - Could not replace original DB file. %v", err)
+			return
 		}
 		fmt.Println("    Overwrote original item DB file.")
+		i.Changed = false // Saved, so Close can be called again later.
+		i.names = nil
+		i.icons = nil
 		return
 	}
 }
 
+// merge will replace DB with the DB file, if it can be read, updated with the
+// names and icon IDs set since the DB was saved.
+func (i *Items) merge() {
+	dat, err := ioutil.ReadFile(i.Fname)
+	if err != nil {
+		return // Nothing to merge with.
+	}
+	disk := make(map[int]Item)
+	if err = yaml.Unmarshal(dat, &disk); err != nil {
+		return // Replace a bad file.
+	}
+	for id := range i.names {
+		item := disk[id]
+		item.Name = i.DB[id].Name
+		disk[id] = item
+	}
+	for id := range i.icons {
+		item := disk[id]
+		item.IconID = i.DB[id].IconID
+		disk[id] = item
+	}
+	i.DB = disk
+}
+
+// setField will note that a field of the id's item was set so that Close
+// keeps it when merging.
+func setField(fields *map[int]bool, id int) {
+	if *fields == nil {
+		*fields = make(map[int]bool)
+	}
+	(*fields)[id] = true
+}
+
 // Name will get the id's item Name.
 func (i *Items) Name(id int) string {
 	return i.DB[id].Name
 }
 
+// FindName will return the IDs of all items with the name. The match ignores
+// case. Some items share a name, so more than one ID may be returned. The IDs
+// are sorted.
+func (i *Items) FindName(name string) []int {
+	var ids []int
+	for id, item := range i.DB {
+		if item.Name != "" && strings.EqualFold(item.Name, name) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// Search will return the IDs of all items whose name contains the text. The
+// match ignores case. The IDs are sorted by item name and then by ID.
+func (i *Items) Search(text string) []int {
+	text = strings.ToLower(text)
+	var ids []int
+	for id, item := range i.DB {
+		if item.Name != "" && strings.Contains(strings.ToLower(item.Name), text) {
+			ids = append(ids, id)
+		}
+	}
+	i.sortByName(ids)
+	return ids
+}
+
+// Match will return the IDs of all items whose name loosely matches the text.
+// Each word of the text must start a word of the name, in any order, ignoring
+// case and punctuation (E.g., "tanaan stein" matches "Stein of Tanaan's"). If
+// no name matches that way, words may also be one letter off from a word of
+// the name to allow for typos. The IDs are sorted by item name and then by ID.
+func (i *Items) Match(text string) []int {
+	words := nameWords(text)
+	if len(words) == 0 {
+		return nil
+	}
+	for _, fuzzy := range []bool{false, true} {
+		var ids []int
+		for id, item := range i.DB {
+			if item.Name != "" && wordsMatch(words, nameWords(item.Name), fuzzy) {
+				ids = append(ids, id)
+			}
+		}
+		if len(ids) > 0 {
+			i.sortByName(ids)
+			return ids
+		}
+	}
+	return nil
+}
+
+// nameWords will return the lower case words of the name. Apostrophes are
+// dropped and other punctuation separates words.
+func nameWords(name string) []string {
+	name = strings.ToLower(strings.ReplaceAll(name, "'", ""))
+	return strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// wordsMatch returns true if every word starts a name word. If fuzzy, a
+// word of four or more letters may also be one edit away from a name word.
+func wordsMatch(words []string, nameWords []string, fuzzy bool) bool {
+	for _, word := range words {
+		found := false
+		for _, nameWord := range nameWords {
+			if strings.HasPrefix(nameWord, word) ||
+				(fuzzy && len(word) >= 4 && oneEdit(word, nameWord)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// oneEdit returns true if the words differ by at most one inserted, deleted,
+// or changed letter.
+func oneEdit(a string, b string) bool {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	if len(b)-len(a) > 1 {
+		return false
+	}
+	n := 0
+	for n < len(a) && a[n] == b[n] {
+		n++
+	}
+	if len(a) == len(b) {
+		return n == len(a) || a[n+1:] == b[n+1:]
+	}
+	return a[n:] == b[n+1:]
+}
+
+// sortByName will sort the IDs by item name and then by ID.
+func (i *Items) sortByName(ids []int) {
+	sort.Slice(ids, func(a, b int) bool {
+		nameA, nameB := i.DB[ids[a]].Name, i.DB[ids[b]].Name
+		if nameA != nameB {
+			return nameA < nameB
+		}
+		return ids[a] < ids[b]
+	})
+}
+
 // IconID will get the id's item IconID.
 func (i *Items) IconID(id int) int {
 	return i.DB[id].IconID
@@ -101,6 +260,7 @@ func (i *Items) SetName(id int, name string) {
 		item.Name = name
 		i.DB[id] = item
 		i.Changed = true
+		setField(&i.names, id)
 	}
 }
 
@@ -112,6 +272,7 @@ func (i *Items) SetIconID(id int, iconID int) {
 		item.IconID = iconID
 		i.DB[id] = item
 		i.Changed = true
+		setField(&i.icons, id)
 	}
 }
 
@@ -121,5 +282,7 @@ func (i *Items) SetItem(id int, item Item) {
 		// Only set if different.
 		i.DB[id] = item
 		i.Changed = true
+		setField(&i.names, id)
+		setField(&i.icons, id)
 	}
 }
@@ -0,0 +1,148 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package eqdb
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// LedgerEntry is a reservation or hand-out of an item. A reservation is
+// pending until it is handed out. A hand-out without a reservation has both
+// times set to the time of the hand-out.
+type LedgerEntry struct {
+	ID        int       // Item ID
+	Count     int       // Number of the item
+	Recipient string    // Who the item is for
+	Address   string    `yaml:",omitempty"` // House holding the item (Optional)
+	Reserved  time.Time // When reserved
+	HandedOut time.Time `yaml:",omitempty"` // When handed out (Zero if pending)
+}
+
+// Pending returns true if the entry is a reservation that has not been handed
+// out.
+func (e LedgerEntry) Pending() bool {
+	return e.HandedOut.IsZero()
+}
+
+// Ledger is the list of item reservations and hand-outs.
+type Ledger struct {
+	Entries []LedgerEntry // All entries in the order recorded
+	Fname   string        // File to hold ledger
+	Changed bool          // Set to true if ledger is altered and should be saved.
+}
+
+// OpenLedger will return a ledger read in from a YAML file. A missing file
+// results in an empty ledger that will be created when saved.
+func OpenLedger(fname string) (Ledger, error) {
+	l := Ledger{Fname: fname}
+	_, err := readYAMLFile(fname, &l.Entries)
+	return l, err
+}
+
+// Close will save the ledger to its YAML file if it changed.
+func (l *Ledger) Close() error {
+	if !l.Changed {
+		return nil
+	}
+	err := writeYAMLFile(l.Fname, l.Entries)
+	if err == nil {
+		l.Changed = false
+	}
+	return err
+}
+
+// Reserve will record a pending reservation of the item for the recipient.
+func (l *Ledger) Reserve(id int, count int, recipient string, address string, when time.Time) {
+	l.Entries = append(l.Entries, LedgerEntry{
+		ID:        id,
+		Count:     count,
+		Recipient: recipient,
+		Address:   address,
+		Reserved:  when,
+	})
+	l.Changed = true
+}
+
+// HandOut will record that the item was handed out to the recipient. Pending
+// reservations of the item for the recipient are used up oldest first until
+// the count is reached. A reservation for more than what is left of the count
+// is split into a handed out part and a part that stays pending with the
+// original reservation time. Anything handed out beyond the reservations is
+// recorded as a new entry.
+func (l *Ledger) HandOut(id int, count int, recipient string, address string, when time.Time) {
+	var entries []LedgerEntry
+	for _, entry := range l.Entries {
+		if count == 0 || !entry.Pending() || entry.ID != id || !strings.EqualFold(entry.Recipient, recipient) {
+			entries = append(entries, entry)
+			continue
+		}
+		handed := entry
+		handed.HandedOut = when
+		if address != "" {
+			handed.Address = address
+		}
+		if entry.Count > count {
+			handed.Count = count
+			entry.Count -= count
+			entries = append(entries, handed, entry) // The rest stays pending
+			count = 0
+			continue
+		}
+		count -= entry.Count
+		entries = append(entries, handed)
+	}
+	l.Entries = entries
+	if count > 0 {
+		l.Entries = append(l.Entries, LedgerEntry{
+			ID:        id,
+			Count:     count,
+			Recipient: recipient,
+			Address:   address,
+			Reserved:  when,
+			HandedOut: when,
+		})
+	}
+	l.Changed = true
+}
+
+// Cancel will remove all pending reservations of the item for the recipient.
+// The number of reservations removed is returned.
+func (l *Ledger) Cancel(id int, recipient string) int {
+	var kept []LedgerEntry
+	removed := 0
+	for _, entry := range l.Entries {
+		if entry.Pending() && entry.ID == id && strings.EqualFold(entry.Recipient, recipient) {
+			removed++
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	if removed > 0 {
+		l.Entries = kept
+		l.Changed = true
+	}
+	return removed
+}
+
+// Pending will return all pending reservations sorted by recipient and then
+// by the time reserved.
+func (l *Ledger) Pending() []LedgerEntry {
+	var pending []LedgerEntry
+	for _, entry := range l.Entries {
+		if entry.Pending() {
+			pending = append(pending, entry)
+		}
+	}
+	sort.SliceStable(pending, func(i, j int) bool {
+		ri, rj := strings.ToLower(pending[i].Recipient), strings.ToLower(pending[j].Recipient)
+		if ri != rj {
+			return ri < rj
+		}
+		return pending[i].Reserved.Before(pending[j].Reserved)
+	})
+	return pending
+}
@@ -0,0 +1,144 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package eqdb
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// LootRecord is an item looted by a character.
+type LootRecord struct {
+	Time      time.Time
+	Character string
+	Item      string // Item name as given in the log
+	ID        int    `yaml:",omitempty"` // Item ID (0 if the name is not in the item DB)
+	Count     int
+	Zone      string `yaml:",omitempty"` // Zone looted in (Empty if not known)
+	From      string `yaml:",omitempty"` // Corpse looted from (Empty if not known)
+}
+
+// lootKey identifies a loot event. The same loot is seen in the logs of each
+// character in the group or raid. A log only has times to the second, so a
+// character can loot the same count of an item more than once with the same
+// key (E.g., from two corpses). These are told apart by the order they are in
+// the log.
+type lootKey struct {
+	Time      int64
+	Character string
+	Item      string
+	Count     int
+}
+
+// key will return the key for the record.
+func (r LootRecord) key() lootKey {
+	return lootKey{r.Time.Unix(), strings.ToLower(r.Character), strings.ToLower(r.Item), r.Count}
+}
+
+// LootHistory is the list of loot records sorted by time.
+type LootHistory struct {
+	Records   []LootRecord // All records, oldest first
+	Fname     string       // File to hold history
+	Changed   bool         // Set to true if history is altered and should be saved.
+	positions map[lootKey][]int
+}
+
+// OpenLootHistory will return the loot history read in from a YAML file. A
+// missing file results in an empty history that will be created when saved.
+func OpenLootHistory(fname string) (LootHistory, error) {
+	h := LootHistory{Fname: fname}
+	_, err := readYAMLFile(fname, &h.Records)
+	return h, err
+}
+
+// Close will save the history to its YAML file if it changed.
+func (h *LootHistory) Close() error {
+	if !h.Changed {
+		return nil
+	}
+	err := writeYAMLFile(h.Fname, h.Records)
+	if err == nil {
+		h.Changed = false
+	}
+	return err
+}
+
+// AddLog will add the records read from one log, in the order they are in the
+// log. The n-th record of the log with the same time (to the second),
+// character, item, and count is only added if the history does not already
+// hold n such records, so reading the same loot again from this log or the
+// log of another character does not add it twice. Missing zones and item IDs
+// of an existing record are filled in from the new record. It returns the
+// number of records added.
+func (h *LootHistory) AddLog(recs []LootRecord) int {
+	if h.positions == nil {
+		h.positions = make(map[lootKey][]int)
+		for n, r := range h.Records {
+			h.positions[r.key()] = append(h.positions[r.key()], n)
+		}
+	}
+	added := 0
+	seen := make(map[lootKey]int) // Records of the log so far with each key
+	for _, rec := range recs {
+		key := rec.key()
+		nth := seen[key]
+		seen[key]++
+		if positions := h.positions[key]; nth < len(positions) {
+			r := &h.Records[positions[nth]]
+			if r.Zone == "" && rec.Zone != "" {
+				r.Zone = rec.Zone
+				h.Changed = true
+			}
+			if r.ID == 0 && rec.ID != 0 {
+				r.ID = rec.ID
+				h.Changed = true
+			}
+			continue
+		}
+		h.insert(rec)
+		added++
+	}
+	return added
+}
+
+// insert will add the record after all records at the same time or earlier
+// and update the positions of the records after it.
+func (h *LootHistory) insert(rec LootRecord) {
+	// Logs are usually read in order, so search from the end.
+	n := len(h.Records)
+	for n > 0 && h.Records[n-1].Time.After(rec.Time) {
+		n--
+	}
+	h.Records = append(h.Records, LootRecord{})
+	copy(h.Records[n+1:], h.Records[n:])
+	h.Records[n] = rec
+	for i := len(h.Records) - 1; i > n; i-- {
+		positions := h.positions[h.Records[i].key()]
+		for j := range positions {
+			if positions[j] == i-1 {
+				positions[j] = i
+				break
+			}
+		}
+	}
+	// Records with the same key are at the same time, so this is the last.
+	h.positions[rec.key()] = append(h.positions[rec.key()], n)
+	h.Changed = true
+}
+
+// Between will return the records from the start time up to but not including
+// the end time. A zero end time has no limit.
+func (h *LootHistory) Between(start time.Time, end time.Time) []LootRecord {
+	n := sort.Search(len(h.Records), func(i int) bool { return !h.Records[i].Time.Before(start) })
+	var records []LootRecord
+	for ; n < len(h.Records); n++ {
+		if !end.IsZero() && !h.Records[n].Time.Before(end) {
+			break
+		}
+		records = append(records, h.Records[n])
+	}
+	return records
+}
@@ -0,0 +1,185 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package eqdb
+
+import (
+	"sort"
+	"strings"
+)
+
+// CompletedQuest is a quest completed by a character. The expansion and zone
+// are kept since quests in different zones can have the same name.
+type CompletedQuest struct {
+	Exp  string // Expansion name
+	Zone string // Zone name
+	Name string // Quest name
+}
+
+// String will return the quest as "EXP / ZONE / QUEST".
+func (q CompletedQuest) String() string {
+	return q.Exp + " / " + q.Zone + " / " + q.Name
+}
+
+// Is returns true if it is the quest (ignoring case).
+func (q CompletedQuest) Is(exp string, zone string, quest string) bool {
+	return strings.EqualFold(q.Exp, exp) && strings.EqualFold(q.Zone, zone) &&
+		strings.EqualFold(q.Name, quest)
+}
+
+// ToonProgress holds the collection progress of a character. Items of a
+// completed quest are all counted as collected even if not in Items.
+type ToonProgress struct {
+	Name   string           // Character name
+	Items  []int            `yaml:",flow,omitempty"` // Collected item IDs (sorted)
+	Quests []CompletedQuest `yaml:",omitempty"`      // Completed quests (sorted)
+}
+
+// HasItem returns true if the item is recorded as collected.
+func (t ToonProgress) HasItem(id int) bool {
+	i := sort.SearchInts(t.Items, id)
+	return i < len(t.Items) && t.Items[i] == id
+}
+
+// HasQuest returns true if the quest in the expansion and zone is recorded as
+// completed.
+func (t ToonProgress) HasQuest(exp string, zone string, quest string) bool {
+	for _, q := range t.Quests {
+		if q.Is(exp, zone, quest) {
+			return true
+		}
+	}
+	return false
+}
+
+// Progress is the collection progress of all characters.
+type Progress struct {
+	Toons   []ToonProgress // Characters sorted by name
+	Fname   string         // File to hold progress
+	Changed bool           // Set to true if progress is altered and should be saved.
+}
+
+// OpenProgress will return the progress read in from a YAML file. A missing
+// file results in empty progress that will be created when saved.
+func OpenProgress(fname string) (Progress, error) {
+	p := Progress{Fname: fname}
+	_, err := readYAMLFile(fname, &p.Toons)
+	for _, t := range p.Toons {
+		sort.Ints(t.Items) // The file may have been edited by hand.
+	}
+	return p, err
+}
+
+// Close will save the progress to its YAML file if it changed.
+func (p *Progress) Close() error {
+	if !p.Changed {
+		return nil
+	}
+	err := writeYAMLFile(p.Fname, p.Toons)
+	if err == nil {
+		p.Changed = false
+	}
+	return err
+}
+
+// Toon will return the progress of the character (ignoring case) or nil if
+// there is none.
+func (p *Progress) Toon(name string) *ToonProgress {
+	for n := range p.Toons {
+		if strings.EqualFold(p.Toons[n].Name, name) {
+			return &p.Toons[n]
+		}
+	}
+	return nil
+}
+
+// toon will return the progress of the character, adding the character if
+// not present.
+func (p *Progress) toon(name string) *ToonProgress {
+	if t := p.Toon(name); t != nil {
+		return t
+	}
+	p.Toons = append(p.Toons, ToonProgress{Name: name})
+	sort.Slice(p.Toons, func(i, j int) bool {
+		return strings.ToLower(p.Toons[i].Name) < strings.ToLower(p.Toons[j].Name)
+	})
+	return p.Toon(name)
+}
+
+// AddItems will record the items as collected by the character. The number of
+// items not already recorded is returned.
+func (p *Progress) AddItems(name string, ids []int) int {
+	t := p.toon(name)
+	added := 0
+	for _, id := range ids {
+		if !t.HasItem(id) {
+			t.Items = append(t.Items, id)
+			sort.Ints(t.Items)
+			added++
+		}
+	}
+	if added > 0 {
+		p.Changed = true
+	}
+	return added
+}
+
+// RemoveItems will remove the items from those collected by the character.
+// The number of items removed is returned.
+func (p *Progress) RemoveItems(name string, ids []int) int {
+	t := p.Toon(name)
+	if t == nil {
+		return 0
+	}
+	var kept []int
+	removed := 0
+	for _, id := range t.Items {
+		found := false
+		for _, rid := range ids {
+			found = found || rid == id
+		}
+		if found {
+			removed++
+			continue
+		}
+		kept = append(kept, id)
+	}
+	if removed > 0 {
+		t.Items = kept
+		p.Changed = true
+	}
+	return removed
+}
+
+// CompleteQuest will record the quest as completed by the character. It
+// returns false if it was already recorded.
+func (p *Progress) CompleteQuest(name string, quest CompletedQuest) bool {
+	t := p.toon(name)
+	if t.HasQuest(quest.Exp, quest.Zone, quest.Name) {
+		return false
+	}
+	t.Quests = append(t.Quests, quest)
+	sort.Slice(t.Quests, func(i, j int) bool {
+		return strings.ToLower(t.Quests[i].String()) < strings.ToLower(t.Quests[j].String())
+	})
+	p.Changed = true
+	return true
+}
+
+// ReopenQuest will remove the quest from those completed by the character. It
+// returns false if it was not recorded.
+func (p *Progress) ReopenQuest(name string, quest CompletedQuest) bool {
+	t := p.Toon(name)
+	if t == nil {
+		return false
+	}
+	for n, q := range t.Quests {
+		if q.Is(quest.Exp, quest.Zone, quest.Name) {
+			t.Quests = append(t.Quests[:n], t.Quests[n+1:]...)
+			p.Changed = true
+			return true
+		}
+	}
+	return false
+}
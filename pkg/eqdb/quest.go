@@ -0,0 +1,229 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package eqdb
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/brianholland99/intlist"
+	"gopkg.in/yaml.v2"
+)
+
+// The quest data is the hierarchy of collection quests. Expansions -> Zones ->
+// Quests. It is maintained in a YAML file in the eqdata repository. The Line
+// fields are not in the file. They are set when reading the file so problems
+// can be reported with their location.
+
+// QuestInfo holds info for a specific collection quest.
+type QuestInfo struct {
+	Name string // Quest name (E.g., "Fear in Pieces")
+	Ids  string // EQ Item IDs contained in quest (E.g., "500...506,519")
+	Note string // Notes about this collection set (E.g, "Drop from undead")
+	Line int    `yaml:"-"` // Line in the quest data file
+}
+
+// IDs will return the item IDs in the quest.
+func (q QuestInfo) IDs() ([]int, error) {
+	return intlist.Parse(q.Ids)
+}
+
+// QuestZone holds collection quests for a zone.
+type QuestZone struct {
+	Name   string      // Zone name (E.g., "Shard's Landing")
+	Quests []QuestInfo // All collection quests in this zone.
+	Line   int         `yaml:"-"` // Line in the quest data file
+}
+
+// QuestExp holds the zones for that expansion. This is the top-level quest
+// structure. Expansions -> Zones -> Quests.
+type QuestExp struct {
+	Name  string      // Expansion name (E.g., "Rain of Fear")
+	Zones []QuestZone // All zones in expansion with collection quests.
+	Line  int         `yaml:"-"` // Line in the quest data file
+}
+
+// ReadQuests will return the quest data from a YAML file.
+func ReadQuests(fname string) ([]QuestExp, error) {
+	dat, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	var quests []QuestExp
+	err = yaml.Unmarshal(dat, &quests)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fname, err)
+	}
+	setQuestLines(quests, dat)
+	return quests, nil
+}
+
+// blockScalar matches a line whose value is a block scalar. The lines of text
+// that follow are indented more than it.
+var blockScalar = regexp.MustCompile(`(^-|:)\s+[|>][-+1-9]*\s*(#.*)?$`)
+
+// setQuestLines will set the Line fields of the quest data read from dat.
+// Each expansion, zone, and quest is a list item, so the lines starting a list
+// item are the expansions, zones, and quests in file order. Their indentation
+// tells them apart. The lines are left at 0 if the list items found do not
+// match the quest data (E.g., for lists written in flow style).
+func setQuestLines(quests []QuestExp, dat []byte) {
+	type listItem struct {
+		Line  int
+		Depth int // 0 for expansions, 1 for zones, and 2 for quests
+	}
+	var items []listItem
+	var indents []int // Indentation of the list items at each depth
+	scalar := -1      // Indentation of the line starting a block scalar
+	for n, line := range strings.Split(string(dat), "\n") {
+		line = strings.TrimRight(line, "\r")
+		text := strings.TrimLeft(line, " ")
+		indent := len(line) - len(text)
+		if scalar >= 0 {
+			if text == "" || indent > scalar {
+				continue // Text of a block scalar (E.g., a note)
+			}
+			scalar = -1
+		}
+		if blockScalar.MatchString(text) {
+			scalar = indent
+		}
+		if text != "-" && !strings.HasPrefix(text, "- ") {
+			continue
+		}
+		for len(indents) > 0 && indents[len(indents)-1] > indent {
+			indents = indents[:len(indents)-1]
+		}
+		if len(indents) == 0 || indents[len(indents)-1] < indent {
+			indents = append(indents, indent)
+		}
+		items = append(items, listItem{Line: n + 1, Depth: len(indents) - 1})
+	}
+
+	// The Line fields in file order with the depth of their list items.
+	var lines []*int
+	var depths []int
+	for i := range quests {
+		lines, depths = append(lines, &quests[i].Line), append(depths, 0)
+		for j := range quests[i].Zones {
+			zone := &quests[i].Zones[j]
+			lines, depths = append(lines, &zone.Line), append(depths, 1)
+			for k := range zone.Quests {
+				lines, depths = append(lines, &zone.Quests[k].Line), append(depths, 2)
+			}
+		}
+	}
+	if len(items) != len(lines) {
+		return
+	}
+	for n, item := range items {
+		if item.Depth != depths[n] {
+			return
+		}
+	}
+	for n, item := range items {
+		*lines[n] = item.Line
+	}
+}
+
+// QuestProblem is a problem found in the quest data.
+type QuestProblem struct {
+	Line    int    // Line in the quest data file
+	Where   string // Expansion / zone / quest names
+	Problem string // Description of the problem
+}
+
+// String will return the problem as a single line.
+func (p QuestProblem) String() string {
+	if p.Line == 0 {
+		return fmt.Sprintf("%s - %s", p.Where, p.Problem) // Line not known
+	}
+	return fmt.Sprintf("line %d: %s - %s", p.Line, p.Where, p.Problem)
+}
+
+// ValidateQuests will check the quest data and return any problems found. If
+// an item DB is given, items without a name in it are also reported. The
+// following are checked:
+//   - Expansions, zones, or quests without names.
+//   - Expansion names used more than once.
+//   - Zone names used more than once in an expansion.
+//   - Expansions without zones and zones without quests.
+//   - Quests with no IDs or IDs that cannot be parsed.
+//   - IDs in more than one quest (or more than once in a quest).
+//   - IDs without a name in the item DB.
+func ValidateQuests(quests []QuestExp, items *Items) []QuestProblem {
+	var problems []QuestProblem
+	add := func(line int, where string, format string, args ...interface{}) {
+		problems = append(problems, QuestProblem{
+			Line:    line,
+			Where:   where,
+			Problem: fmt.Sprintf(format, args...),
+		})
+	}
+	type idLoc struct {
+		Line  int
+		Where string
+	}
+	idLocs := make(map[int]idLoc) // Where each ID was first found
+	expLines := make(map[string]int)
+	for _, exp := range quests {
+		if exp.Name == "" {
+			add(exp.Line, "(expansion)", "expansion has no name")
+		} else if line, ok := expLines[exp.Name]; ok {
+			add(exp.Line, exp.Name, "expansion name also used at line %d", line)
+		} else {
+			expLines[exp.Name] = exp.Line
+		}
+		if len(exp.Zones) == 0 {
+			add(exp.Line, exp.Name, "expansion has no zones")
+		}
+		zoneLines := make(map[string]int)
+		for _, zone := range exp.Zones {
+			zoneWhere := exp.Name + " / " + zone.Name
+			if zone.Name == "" {
+				add(zone.Line, zoneWhere, "zone has no name")
+			} else if line, ok := zoneLines[zone.Name]; ok {
+				add(zone.Line, zoneWhere, "zone name also used at line %d", line)
+			} else {
+				zoneLines[zone.Name] = zone.Line
+			}
+			if len(zone.Quests) == 0 {
+				add(zone.Line, zoneWhere, "zone has no quests")
+			}
+			for _, quest := range zone.Quests {
+				where := zoneWhere + " / " + quest.Name
+				if quest.Name == "" {
+					add(quest.Line, where, "quest has no name")
+				}
+				ids, err := quest.IDs()
+				if err != nil {
+					add(quest.Line, where, "bad ids \"%s\" - %v", quest.Ids, err)
+					continue
+				}
+				if len(ids) == 0 {
+					add(quest.Line, where, "quest has no ids")
+				}
+				var unnamed []string
+				for _, id := range ids {
+					if loc, ok := idLocs[id]; ok {
+						add(quest.Line, where, "id %d is also in %s at line %d", id, loc.Where, loc.Line)
+					} else {
+						idLocs[id] = idLoc{Line: quest.Line, Where: where}
+					}
+					if items != nil && items.Name(id) == "" {
+						unnamed = append(unnamed, strconv.Itoa(id))
+					}
+				}
+				if len(unnamed) > 0 {
+					add(quest.Line, where, "no item DB name for ids %s", strings.Join(unnamed, ", "))
+				}
+			}
+		}
+	}
+	return problems
+}
@@ -0,0 +1,138 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package eqdb
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// RosterMember is a guild member in a roster snapshot.
+type RosterMember struct {
+	Name   string
+	Level  int
+	Class  string
+	Rank   string
+	Alt    bool      `yaml:",omitempty"`
+	LastOn time.Time `yaml:",omitempty"` // Date last online (Zero if never)
+	Zone   string    `yaml:",omitempty"`
+	Notes  string    `yaml:",omitempty"`
+}
+
+// RosterSnapshot holds the guild members at a time.
+type RosterSnapshot struct {
+	Time    time.Time
+	Members []RosterMember // Sorted by name
+}
+
+// Member will return the member with the name (ignoring case) and true, or
+// false if there is no such member.
+func (s RosterSnapshot) Member(name string) (RosterMember, bool) {
+	for _, m := range s.Members {
+		if strings.EqualFold(m.Name, name) {
+			return m, true
+		}
+	}
+	return RosterMember{}, false
+}
+
+// Roster is the list of guild roster snapshots in the order taken.
+type Roster struct {
+	Snapshots []RosterSnapshot // All snapshots, oldest first
+	Fname     string           // File to hold roster
+	Changed   bool             // Set to true if roster is altered and should be saved.
+}
+
+// OpenRoster will return the roster read in from a YAML file. A missing file
+// results in an empty roster that will be created when saved.
+func OpenRoster(fname string) (Roster, error) {
+	r := Roster{Fname: fname}
+	_, err := readYAMLFile(fname, &r.Snapshots)
+	return r, err
+}
+
+// Close will save the roster to its YAML file if it changed.
+func (r *Roster) Close() error {
+	if !r.Changed {
+		return nil
+	}
+	err := writeYAMLFile(r.Fname, r.Snapshots)
+	if err == nil {
+		r.Changed = false
+	}
+	return err
+}
+
+// Add will add the snapshot to the roster in time order unless the members
+// are the same as the snapshot before it. It returns true if the snapshot was
+// added.
+func (r *Roster) Add(snap RosterSnapshot) bool {
+	sort.Slice(snap.Members, func(i, j int) bool {
+		return strings.ToLower(snap.Members[i].Name) < strings.ToLower(snap.Members[j].Name)
+	})
+	n := len(r.Snapshots)
+	for n > 0 && r.Snapshots[n-1].Time.After(snap.Time) {
+		n--
+	}
+	if n > 0 && sameMembers(r.Snapshots[n-1].Members, snap.Members) {
+		return false
+	}
+	r.Snapshots = append(r.Snapshots, RosterSnapshot{})
+	copy(r.Snapshots[n+1:], r.Snapshots[n:])
+	r.Snapshots[n] = snap
+	r.Changed = true
+	return true
+}
+
+// sameMembers returns true if the member lists are the same.
+func sameMembers(a, b []RosterMember) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for n := range a {
+		x, y := a[n], b[n]
+		if !x.LastOn.Equal(y.LastOn) {
+			return false
+		}
+		x.LastOn, y.LastOn = time.Time{}, time.Time{}
+		if x != y {
+			return false
+		}
+	}
+	return true
+}
+
+// Latest will return the latest snapshot and true, or false if there are no
+// snapshots.
+func (r *Roster) Latest() (RosterSnapshot, bool) {
+	if len(r.Snapshots) == 0 {
+		return RosterSnapshot{}, false
+	}
+	return r.Snapshots[len(r.Snapshots)-1], true
+}
+
+// RosterChanges lists the members that joined or left between two snapshots.
+type RosterChanges struct {
+	Joined []RosterMember
+	Left   []RosterMember
+}
+
+// DiffRoster will return the members in 'after' that are not in 'before' as
+// joined and the members in 'before' that are not in 'after' as left.
+func DiffRoster(before RosterSnapshot, after RosterSnapshot) RosterChanges {
+	var changes RosterChanges
+	for _, m := range after.Members {
+		if _, ok := before.Member(m.Name); !ok {
+			changes.Joined = append(changes.Joined, m)
+		}
+	}
+	for _, m := range before.Members {
+		if _, ok := after.Member(m.Name); !ok {
+			changes.Left = append(changes.Left, m)
+		}
+	}
+	return changes
+}
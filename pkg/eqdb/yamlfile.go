@@ -0,0 +1,52 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package eqdb
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+// writeYAMLFile will save the data as YAML. The data is written to a
+// temporary file in the same directory that then replaces the original file,
+// so the original file is left in place if anything fails.
+func writeYAMLFile(fname string, data interface{}) error {
+	dat, err := yaml.Marshal(data)
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(fname), "tempdb")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // No-op once renamed.
+	_, err = f.Write(dat)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	err = f.Close() // Must be closed before Rename().
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), fname)
+}
+
+// readYAMLFile will read the YAML file into the data. A missing file is not
+// an error and leaves the data unchanged. The returned bool is true if the
+// file existed.
+func readYAMLFile(fname string, data interface{}) (bool, error) {
+	dat, err := ioutil.ReadFile(fname)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, yaml.Unmarshal(dat, data)
+}
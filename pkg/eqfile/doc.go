@@ -25,6 +25,12 @@ includes the following file types:
   - RealEstate
   - Inventory
   - Loot Filter
+  - Chat Log
+  - Spellbook
+  - Missing Spells
+  - Guild Roster
+  - Character UI (INI)
+  - Client Settings (eqclient.ini)
 
 File formats - I did not find any documented format so determined the formats
 by inspection. They each have one header line followed by data lines. Each data
@@ -63,5 +69,77 @@ where TYPE is one of (AN - Always Need, AG - Always Greed, Nvr - Never, or Rnd
 - Random). These files contain data for the last retained setting of the item.
 Only items being updated at the time are updated in the file, so many entries
 may have old names.
+
+Spellbook
+
+Spellbook files are created by the "/outputfile spellbook [FILENAME]" command
+in EQ. They list the level and name of each spell in the spellbook of the user
+executing the command. The default file name is of the form
+"{TOON}_{SERVER}-Spellbook.txt" and found in the EQ install directory.
+
+Missing Spells
+
+Missing spells files are created by the "/outputfile missingspells [FILENAME]"
+command in EQ. They list the level, name, and type of each spell the user
+executing the command can use but does not have. The default file name is of
+the form "{TOON}_{SERVER}-MissingSpells.txt" and found in the EQ install
+directory.
+
+Guild Roster
+
+Guild roster files are created by the "/outputfile guild [FILENAME]" command in
+EQ. They list each member of the guild of the user executing the command with
+their level, class, rank, alt flag, date last online, zone, and public note.
+The default file name is of the form "{GUILD}_{SERVER}-{DATE}.txt" and found
+in the EQ install directory.
+
+Character UI files
+
+Character UI files are INI format files written by EQ for each character with
+names of the form "{TOON}_{SERVER}.ini" in the EQ install directory. They hold
+the socials (macros) in the "[Socials]" section and the hot buttons in the
+"[HotButtons]" section and one "[HotButtonsN]" section for each other hot
+button window. EQ rewrites these files when the character camps, so they
+should only be changed while the character is logged out.
+
+Unlike the other files, INI files can also be written. An INI keeps every
+line, including comments, blank lines, and spacing, in its original order. A
+file written back only differs in the values that were changed.
+
+Client Settings
+
+The client settings file "eqclient.ini" in the EQ install directory is also an
+INI format file. Key bindings are kept in the "[KeyMaps]" section of it or of a
+character UI file, one "KEYMAPPING_{ACTION}_{SLOT}" key per binding where the
+slot is 1 for the primary and 2 for the alternate binding. Each value is an
+integer key code including modifiers, with 0 meaning not bound. CheckKeyMaps
+reports values that are not integers and key codes bound to more than one
+action.
+
+Chat Logs
+
+Chat log files are written by EQ while logging is turned on with "/log on".
+The file name is of the form "eqlog_{TOON}_{SERVER}.txt" and found in the
+"Logs" folder of the EQ install directory. Unlike the other files, there is no
+header line. Each line starts with a time stamp such as
+"[Mon Oct 19 09:00:00 2020]" followed by the message. Lines for loot, tells,
+guild chat, zoning, experience, faction, and deaths are classified and their
+fields split out. All other lines are kept as EventOther with the message.
+Messages are in English and may change with EQ patches, so a changed message
+is no longer classified rather than being an error. A LogTail can be used to
+follow a log that EQ is still writing.
+
+Watching files
+
+A Watcher can be used to poll any of these files for changes so that a program
+can re-read them after a new "/output" command. Since a file could be polled
+while EQ is still writing it, a change is only reported once the file's size
+and modification time stop changing.
+
+Replacing files
+
+WriteTemp and ReplaceFile write a file to a temporary file in the same
+directory first, so a file being replaced is left in place if anything fails.
+BackupFile copies a file to a backup named with the time before it is changed.
 */
 package eqfile
@@ -0,0 +1,288 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package eqfile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EventType is the kind of a chat log line.
+type EventType string
+
+// Chat log event types. Lines that are not classified are EventOther.
+const (
+	EventOther   EventType = "other"
+	EventLoot    EventType = "loot"    // Who looted Count of Item (From is the corpse)
+	EventTell    EventType = "tell"    // Who sent Text to Target
+	EventGuild   EventType = "guild"   // Who said Text to the guild
+	EventZone    EventType = "zone"    // Entered the Target zone
+	EventExp     EventType = "exp"     // Gained experience (Text is the kind)
+	EventFaction EventType = "faction" // Target faction changed by Count
+	EventDeath   EventType = "death"   // Target was slain by Who
+)
+
+// LogEvent is the data found in a line of a chat log. "You" is used for the
+// character writing the log.
+type LogEvent struct {
+	Time   time.Time // Time stamp of the line (Local time)
+	Type   EventType
+	Msg    string // Message after the time stamp
+	Who    string // Speaker, looter, or killer
+	Target string // Tell recipient, zone, faction, or who was slain
+	Item   string // Looted item
+	From   string // Corpse the item was looted from (May be empty)
+	Count  int    // Number of items looted or faction change
+	Text   string // Tell or guild text, or experience kind
+	Line   int    // Line number in the file
+}
+
+// logTimeLayout is the layout of the time stamp at the start of each line.
+const logTimeLayout = "Mon Jan 02 15:04:05 2006"
+
+// Patterns for the classified lines. These are tried in order.
+var (
+	lootRe       = regexp.MustCompile(`^--(You|\S+) (?:have|has) looted (an?|\d+) (.+?)(?: from (.+?)(?:'s? corpse)?)?\.--$`)
+	tellToYouRe  = regexp.MustCompile(`^(\S+) tells you, '(.*)'$`)
+	tellFromYoRe = regexp.MustCompile(`^You told (\S+), '(.*)'$`)
+	guildRe      = regexp.MustCompile(`^(\S+) tells the guild, '(.*)'$`)
+	guildYouRe   = regexp.MustCompile(`^You say to your guild, '(.*)'$`)
+	zoneRe       = regexp.MustCompile(`^You have entered (.+)\.$`)
+	expRe        = regexp.MustCompile(`^You gain(?:ed)? ((?:party |raid )?experience)`)
+	factionRe    = regexp.MustCompile(`^Your faction standing with (.+?) (?:has been adjusted by (-?\d+)|got (better|worse)|could not possibly get any (better|worse))\.$`)
+	slainByRe    = regexp.MustCompile(`^(You|.+?) (?:have|has) been slain by (.+?)!$`)
+	youSlainRe   = regexp.MustCompile(`^You have slain (.+?)!$`)
+	diedRe       = regexp.MustCompile(`^(.+?) died\.$`)
+)
+
+// ParseLogLine will return the event for a chat log line. The line must start
+// with a time stamp of the form "[Mon Oct 19 09:00:00 2020] ".
+func ParseLogLine(line string) (LogEvent, error) {
+	line = strings.TrimRight(line, "\r")
+	end := strings.Index(line, "] ")
+	if !strings.HasPrefix(line, "[") || end < 0 {
+		return LogEvent{}, fmt.Errorf("missing time stamp")
+	}
+	t, err := time.ParseInLocation(logTimeLayout, line[1:end], time.Local)
+	if err != nil {
+		return LogEvent{}, fmt.Errorf("bad time stamp \"%s\"", line[1:end])
+	}
+	ev := LogEvent{Time: t, Type: EventOther, Msg: line[end+2:]}
+	classify(&ev)
+	return ev, nil
+}
+
+// classify will set the type and fields of the event from its message.
+func classify(ev *LogEvent) {
+	msg := ev.Msg
+	if m := lootRe.FindStringSubmatch(msg); m != nil {
+		ev.Type, ev.Who, ev.Item, ev.From = EventLoot, m[1], m[3], m[4]
+		ev.Count = 1
+		if n, err := strconv.Atoi(m[2]); err == nil {
+			ev.Count = n
+		}
+	} else if m := tellToYouRe.FindStringSubmatch(msg); m != nil {
+		ev.Type, ev.Who, ev.Target, ev.Text = EventTell, m[1], "You", m[2]
+	} else if m := tellFromYoRe.FindStringSubmatch(msg); m != nil {
+		ev.Type, ev.Who, ev.Target, ev.Text = EventTell, "You", m[1], m[2]
+	} else if m := guildRe.FindStringSubmatch(msg); m != nil {
+		ev.Type, ev.Who, ev.Text = EventGuild, m[1], m[2]
+	} else if m := guildYouRe.FindStringSubmatch(msg); m != nil {
+		ev.Type, ev.Who, ev.Text = EventGuild, "You", m[1]
+	} else if m := zoneRe.FindStringSubmatch(msg); m != nil && !strings.Contains(m[1], " area") {
+		// "You have entered an area where ..." messages are not zoning.
+		ev.Type, ev.Target = EventZone, m[1]
+	} else if m := expRe.FindStringSubmatch(msg); m != nil {
+		ev.Type, ev.Text = EventExp, m[1]
+	} else if m := factionRe.FindStringSubmatch(msg); m != nil {
+		ev.Type, ev.Target = EventFaction, m[1]
+		switch {
+		case m[2] != "":
+			ev.Count, _ = strconv.Atoi(m[2])
+		case m[3] == "better":
+			ev.Count = 1
+		case m[3] == "worse":
+			ev.Count = -1
+		}
+	} else if m := slainByRe.FindStringSubmatch(msg); m != nil {
+		ev.Type, ev.Target, ev.Who = EventDeath, m[1], m[2]
+	} else if m := youSlainRe.FindStringSubmatch(msg); m != nil {
+		ev.Type, ev.Target, ev.Who = EventDeath, m[1], "You"
+	} else if m := diedRe.FindStringSubmatch(msg); m != nil {
+		ev.Type, ev.Target = EventDeath, m[1]
+	}
+}
+
+// ParseLogName will return the character and server from a chat log file
+// name of the form "eqlog_{TOON}_{SERVER}.txt". The last return is false if
+// the name does not have that form.
+func ParseLogName(fname string) (toon string, server string, ok bool) {
+	base := filepath.Base(fname)
+	if !strings.HasPrefix(base, "eqlog_") || !strings.HasSuffix(base, ".txt") {
+		return "", "", false
+	}
+	parts := strings.SplitN(strings.TrimSuffix(strings.TrimPrefix(base, "eqlog_"), ".txt"), "_", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// SkippedLinesError is returned by ReadLog together with the events when
+// lines without a valid time stamp were skipped.
+type SkippedLinesError struct {
+	Fname   string
+	Skipped int // Number of lines skipped
+	First   int // Line number of the first line skipped
+}
+
+func (e *SkippedLinesError) Error() string {
+	return fmt.Sprintf("%s - skipped %d lines without a valid time stamp (first at line %d)", e.Fname, e.Skipped, e.First)
+}
+
+// ReadLog will return the events in the chat log file.
+//
+// Each line of a chat log starts with a time stamp in square brackets followed
+// by the message. There is no header line. Empty lines are skipped. A line
+// without a valid time stamp (E.g., one cut short when EQ crashed) is skipped
+// rather than failing the whole file. The events of the other lines are then
+// returned with a *SkippedLinesError.
+//
+// Error reasons:
+//   - File cannot be opened or read.
+//   - Lines without a valid time stamp were skipped (*SkippedLinesError).
+func ReadLog(fname string) ([]LogEvent, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	skip := SkippedLinesError{Fname: fname}
+	events, _, _, err := scanLog(f, fname, 0, true, &skip)
+	if err != nil {
+		return nil, err
+	}
+	if skip.Skipped > 0 {
+		return events, &skip
+	}
+	return events, nil
+}
+
+// scanLog will return the events for the lines read from r, the number of
+// bytes and lines used, and any error. If skip is nil, a line without a
+// valid time stamp is an error. The events before the bad line are then
+// returned and the bad line is counted as used. Otherwise, bad lines are
+// skipped and counted in skip. Line numbers start after lineNo. If
+// partial is false, a last line without a newline is not used so that a line
+// EQ is still writing is left for the next read.
+func scanLog(r io.Reader, fname string, lineNo int, partial bool, skip *SkippedLinesError) ([]LogEvent, int64, int, error) {
+	var events []LogEvent
+	var used int64
+	lines := 0
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, used, lines, err
+		}
+		if err == io.EOF && (line == "" || !partial) {
+			return events, used, lines, nil
+		}
+		used += int64(len(line))
+		lines++
+		if text := strings.TrimRight(line, "\r\n"); text != "" {
+			ev, perr := ParseLogLine(text)
+			switch {
+			case perr == nil:
+				ev.Line = lineNo + lines
+				events = append(events, ev)
+			case skip != nil:
+				if skip.Skipped == 0 {
+					skip.First = lineNo + lines
+				}
+				skip.Skipped++
+			default:
+				return events, used, lines, fmt.Errorf("%s at line %d %v", fname, lineNo+lines, perr)
+			}
+		}
+		if err == io.EOF {
+			return events, used, lines, nil
+		}
+	}
+}
+
+// LogTail follows a chat log that EQ is writing to. Each call to Read returns
+// the events for the lines added since the last call.
+type LogTail struct {
+	fname  string
+	offset int64 // Bytes already read
+	lineNo int   // Lines already read
+}
+
+// NewLogTail will return a LogTail for the chat log. If fromEnd is true, the
+// lines already in the file are skipped. The file does not need to exist yet.
+func NewLogTail(fname string, fromEnd bool) *LogTail {
+	t := &LogTail{fname: fname}
+	if !fromEnd {
+		return t
+	}
+	f, err := os.Open(fname)
+	if err != nil {
+		return t
+	}
+	defer f.Close()
+	// Count the lines so line numbers of later events are right.
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			break
+		}
+		t.offset += int64(len(line))
+		t.lineNo++
+	}
+	return t
+}
+
+// Read will return the events for the complete lines added since the last
+// call. If the file is shorter than before (E.g., it was archived and
+// restarted), it is read again from the start. A missing file returns no
+// events. On an error, the events before the bad line are returned along with
+// the error and the bad line is skipped so that following reads continue.
+//
+// Error reasons:
+//   - File cannot be opened for reading.
+//   - A line does not start with a valid time stamp.
+func (t *LogTail) Read() ([]LogEvent, error) {
+	f, err := os.Open(t.fname)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() < t.offset {
+		t.offset, t.lineNo = 0, 0
+	}
+	if _, err = f.Seek(t.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	events, used, lines, err := scanLog(f, t.fname, t.lineNo, false, nil)
+	t.offset += used
+	t.lineNo += lines
+	return events, err
+}
@@ -0,0 +1,100 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package eqfile
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GuildMember is the data found in each line of a guild roster file.
+type GuildMember struct {
+	Name   string
+	Level  int
+	Class  string
+	Rank   string    // E.g., Leader, Officer, Member
+	Alt    bool      // Member is flagged as an alt
+	LastOn time.Time // Date last online (Local time, zero if never)
+	Zone   string    // Zone last seen in
+	Notes  string    // Public note
+}
+
+// Expected guild roster header line
+const guildHeader = "Name\tLevel\tClass\tRank\tAlt\tLast On\tZone\tNotes"
+
+// guildDateLayout is the layout of the "Last On" column.
+const guildDateLayout = "01/02/06"
+
+// ReadGuild will return an array of GuildMembers from the guild roster file.
+//
+// The format appears to be eight columns (Name, Level, Class, Rank, Alt, Last
+// On, Zone, and Notes) separated by a tab character. The first line is a
+// header line. The Alt column is "A" for alts and empty otherwise. The Last On
+// column is a date of the form "MM/DD/YY".
+//
+// This will do some simple checking of the file format and if any issues are
+// identified, it will return an error instead of any data.
+//
+// Error reasons:
+//   - File cannot be opened for reading.
+//   - Header line does not match expected header.
+//   - Column 2 is not an integer.
+//   - Column 6 is not empty or a date.
+func ReadGuild(fname string) ([]GuildMember, error) {
+	var members []GuildMember
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	// Check header line
+	_ = scanner.Scan()
+	head := scanner.Text()
+	if head != guildHeader {
+		// Unexpected header line. Either not a guild roster file
+		// or format may have changed.
+		return nil, fmt.Errorf("%s: missing expected header of \"%s\"",
+			fname, guildHeader)
+	}
+	lineNo := 1
+	for scanner.Scan() {
+		line := scanner.Text()
+		lineNo++
+		parts := strings.Split(line, "\t")
+		if len(parts) != 8 {
+			return nil, fmt.Errorf("%s at line %d has %d columns instead of 8",
+				fname, lineNo, len(parts))
+		}
+		level, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s at line %d column 2 is not an integer",
+				fname, lineNo)
+		}
+		var lastOn time.Time
+		if parts[5] != "" {
+			lastOn, err = time.ParseInLocation(guildDateLayout, parts[5], time.Local)
+			if err != nil {
+				return nil, fmt.Errorf("%s at line %d column 6 is not a date",
+					fname, lineNo)
+			}
+		}
+		members = append(members, GuildMember{
+			Name:   parts[0],
+			Level:  level,
+			Class:  parts[2],
+			Rank:   parts[3],
+			Alt:    parts[4] == "A",
+			LastOn: lastOn,
+			Zone:   parts[6],
+			Notes:  parts[7],
+		})
+	}
+	return members, nil
+}
@@ -0,0 +1,260 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package eqfile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// INI is an INI format file such as a character UI file or eqclient.ini. The
+// lines are kept in order with their comments and spacing so that a file
+// written back only differs in the values that were changed.
+type INI struct {
+	Head     []string      // Lines without a key before the first section
+	Sections []*INISection // Sections in file order
+	CRLF     bool          // Lines end in "\r\n" (As written by EQ)
+}
+
+// INISection is a section of an INI file. The raw header line is written back
+// so that spacing and comments on it are kept.
+type INISection struct {
+	Name  string
+	raw   string // Header line as read (Empty for added sections)
+	lines []iniLine
+}
+
+// iniLine is a line in a section. Comment, blank, and other lines without a
+// key are kept as they are. The raw line is written back unless the value was
+// changed.
+type iniLine struct {
+	Key   string
+	Value string
+	Raw   string
+}
+
+// ReadINI will return the INI file.
+//
+// Sections start with a "[NAME]" line that may end in a comment. Other lines
+// are "KEY=VALUE" lines, comment lines starting with ";" or "#", or blank
+// lines. Any other line is kept as it is without being used. Keys and section
+// names are matched ignoring case as EQ does.
+//
+// Error reasons:
+//   - File cannot be opened for reading.
+//   - A key line comes before the first section.
+func ReadINI(fname string) (*INI, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseINI(f, fname)
+}
+
+// ParseINI will return the INI read from r. The name is only used in errors.
+func ParseINI(r io.Reader, fname string) (*INI, error) {
+	ini := &INI{}
+	var section *INISection
+	reader := bufio.NewReader(r)
+	lineNo := 0
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if line == "" && err == io.EOF {
+			break
+		}
+		lineNo++
+		if strings.HasSuffix(line, "\r\n") {
+			ini.CRLF = true
+		}
+		line = strings.TrimRight(line, "\r\n")
+		text := strings.TrimSpace(line)
+		name, header := iniSectionName(text)
+		switch {
+		case header:
+			section = &INISection{Name: name, raw: line}
+			ini.Sections = append(ini.Sections, section)
+		case strings.Contains(text, "=") && !iniComment(text):
+			if section == nil {
+				return nil, fmt.Errorf("%s at line %d has a key before the first section",
+					fname, lineNo)
+			}
+			n := strings.Index(text, "=")
+			section.lines = append(section.lines, iniLine{
+				Key:   strings.TrimSpace(text[:n]),
+				Value: strings.TrimSpace(text[n+1:]),
+				Raw:   line,
+			})
+		case section == nil:
+			ini.Head = append(ini.Head, line)
+		default:
+			section.lines = append(section.lines, iniLine{Raw: line})
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	return ini, nil
+}
+
+// iniComment returns true if the trimmed line is a comment.
+func iniComment(text string) bool {
+	return strings.HasPrefix(text, ";") || strings.HasPrefix(text, "#")
+}
+
+// iniSectionName will return the section name and true if the trimmed line is
+// a section header. Only a comment may follow the closing bracket.
+func iniSectionName(text string) (string, bool) {
+	end := strings.Index(text, "]")
+	if !strings.HasPrefix(text, "[") || end < 0 {
+		return "", false
+	}
+	if rest := strings.TrimSpace(text[end+1:]); rest != "" && !iniComment(rest) {
+		return "", false
+	}
+	return strings.TrimSpace(text[1:end]), true
+}
+
+// Write will output the INI. Unchanged lines are written as they were read.
+func (ini *INI) Write(w io.Writer) error {
+	eol := "\n"
+	if ini.CRLF {
+		eol = "\r\n"
+	}
+	bw := bufio.NewWriter(w)
+	for _, line := range ini.Head {
+		bw.WriteString(line + eol)
+	}
+	for _, section := range ini.Sections {
+		if section.raw != "" {
+			bw.WriteString(section.raw + eol)
+		} else {
+			bw.WriteString("[" + section.Name + "]" + eol)
+		}
+		for _, line := range section.lines {
+			bw.WriteString(line.Raw + eol)
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteFile will save the INI to the file. It is written to a temporary file
+// that then replaces the file, so the file is not left partly written if there
+// is an error.
+func (ini *INI) WriteFile(fname string) error {
+	return ReplaceFile(fname, func(w *bufio.Writer) error {
+		return ini.Write(w)
+	})
+}
+
+// Section will return the section with the name (ignoring case) or nil if
+// there is none.
+func (ini *INI) Section(name string) *INISection {
+	for _, section := range ini.Sections {
+		if strings.EqualFold(section.Name, name) {
+			return section
+		}
+	}
+	return nil
+}
+
+// AddSection will return the section with the name, adding it at the end if
+// there is none.
+func (ini *INI) AddSection(name string) *INISection {
+	if section := ini.Section(name); section != nil {
+		return section
+	}
+	section := &INISection{Name: name}
+	ini.Sections = append(ini.Sections, section)
+	return section
+}
+
+// Get will return the value of the key in the section and true, or false if
+// there is no such key.
+func (ini *INI) Get(section string, key string) (string, bool) {
+	if s := ini.Section(section); s != nil {
+		return s.Get(key)
+	}
+	return "", false
+}
+
+// Set will set the value of the key in the section, adding either if needed.
+func (ini *INI) Set(section string, key string, value string) {
+	ini.AddSection(section).Set(key, value)
+}
+
+// Delete will remove the key from the section if it is there.
+func (ini *INI) Delete(section string, key string) {
+	if s := ini.Section(section); s != nil {
+		s.Delete(key)
+	}
+}
+
+// Keys will return the keys of the section in file order.
+func (s *INISection) Keys() []string {
+	var keys []string
+	for _, line := range s.lines {
+		if line.Key != "" {
+			keys = append(keys, line.Key)
+		}
+	}
+	return keys
+}
+
+// Get will return the value of the key and true, or false if there is no such
+// key. If a key is repeated, the last value is returned as EQ uses it.
+func (s *INISection) Get(key string) (string, bool) {
+	for n := len(s.lines) - 1; n >= 0; n-- {
+		if s.lines[n].Key != "" && strings.EqualFold(s.lines[n].Key, key) {
+			return s.lines[n].Value, true
+		}
+	}
+	return "", false
+}
+
+// Set will set the value of the key (Every line if repeated). A new key is
+// added after the last key of the section so that trailing comments and blank
+// lines stay at the end.
+func (s *INISection) Set(key string, value string) {
+	last := -1
+	found := false
+	for n := range s.lines {
+		line := &s.lines[n]
+		if line.Key == "" {
+			continue
+		}
+		last = n
+		if strings.EqualFold(line.Key, key) {
+			found = true
+			if line.Value != value {
+				line.Value = value
+				line.Raw = line.Key + "=" + value
+			}
+		}
+	}
+	if found {
+		return
+	}
+	lines := append([]iniLine{}, s.lines[:last+1]...)
+	lines = append(lines, iniLine{Key: key, Value: value, Raw: key + "=" + value})
+	s.lines = append(lines, s.lines[last+1:]...)
+}
+
+// Delete will remove the key if it is there.
+func (s *INISection) Delete(key string) {
+	var lines []iniLine
+	for _, line := range s.lines {
+		if line.Key == "" || !strings.EqualFold(line.Key, key) {
+			lines = append(lines, line)
+		}
+	}
+	s.lines = lines
+}
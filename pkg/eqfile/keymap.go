@@ -0,0 +1,124 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package eqfile
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// KeyMap is a key binding from the "[KeyMaps]" section of eqclient.ini or a
+// character UI file (E.g., "KEYMAPPING_JUMP_1=32").
+type KeyMap struct {
+	Key    string // Key in the section (E.g., "KEYMAPPING_JUMP_1")
+	Action string // Key without the slot (E.g., "KEYMAPPING_JUMP")
+	Slot   int    // 1 for the primary and 2 for the alternate binding (0 if none)
+	Code   int    // Key code including modifiers (0 if not bound)
+}
+
+// keyMapsSection is the section holding key bindings.
+const keyMapsSection = "KeyMaps"
+
+var keyMapKeyRe = regexp.MustCompile(`^(.+)_(\d+)$`)
+
+// parseKeyMap will return the binding for the key and value.
+func parseKeyMap(key string, value string) (KeyMap, error) {
+	code, err := strconv.Atoi(value)
+	if err != nil {
+		return KeyMap{}, fmt.Errorf("[%s] %s has value \"%s\" that is not a number",
+			keyMapsSection, key, value)
+	}
+	k := KeyMap{Key: key, Action: key, Code: code}
+	if m := keyMapKeyRe.FindStringSubmatch(key); m != nil {
+		k.Action = m[1]
+		k.Slot, _ = strconv.Atoi(m[2])
+	}
+	return k, nil
+}
+
+// KeyMaps will return the key bindings in file order.
+//
+// Error reasons:
+//   - A value is not an integer.
+func (ini *INI) KeyMaps() ([]KeyMap, error) {
+	section := ini.Section(keyMapsSection)
+	if section == nil {
+		return nil, nil
+	}
+	var maps []KeyMap
+	for _, key := range section.Keys() {
+		value, _ := section.Get(key)
+		k, err := parseKeyMap(key, value)
+		if err != nil {
+			return nil, err
+		}
+		maps = append(maps, k)
+	}
+	return maps, nil
+}
+
+// SetKeyMap will set the code of the binding.
+func (ini *INI) SetKeyMap(k KeyMap) {
+	ini.Set(keyMapsSection, k.Key, strconv.Itoa(k.Code))
+}
+
+// CheckKeyMaps will return the problems with the key bindings. Unlike
+// KeyMaps, it does not stop at the first problem. An empty list means the
+// bindings are valid.
+//
+// Problems found:
+//   - A value is not an integer.
+//   - The same key code (including modifiers) is bound to more than one
+//     action. Codes of 0 are not bound and are not checked.
+func (ini *INI) CheckKeyMaps() []error {
+	section := ini.Section(keyMapsSection)
+	if section == nil {
+		return nil
+	}
+	var problems []error
+	byCode := make(map[int][]KeyMap)
+	for _, key := range section.Keys() {
+		value, _ := section.Get(key)
+		k, err := parseKeyMap(key, value)
+		if err != nil {
+			problems = append(problems, err)
+			continue
+		}
+		if k.Code != 0 {
+			byCode[k.Code] = append(byCode[k.Code], k)
+		}
+	}
+	var codes []int
+	for code := range byCode {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		var actions []string
+		for _, k := range byCode[code] {
+			if !containsFold(actions, k.Action) {
+				actions = append(actions, k.Action)
+			}
+		}
+		if len(actions) > 1 {
+			problems = append(problems, fmt.Errorf("[%s] code %d is bound to %s",
+				keyMapsSection, code, strings.Join(actions, " and ")))
+		}
+	}
+	return problems
+}
+
+// containsFold returns true if the string is in the list (ignoring case).
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
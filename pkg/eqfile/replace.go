@@ -0,0 +1,96 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package eqfile
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// WriteTemp will create a temporary file in the same directory as the file and
+// pass a buffered writer for it to the 'write' function. The name of the
+// temporary file is returned so that it can replace the file. The temporary
+// file is removed if there is an error. It is given the permissions of the
+// file if it exists so that replacing it does not change them.
+func WriteTemp(fname string, write func(w *bufio.Writer) error) (string, error) {
+	f, err := ioutil.TempFile(filepath.Dir(fname), "tempout")
+	if err != nil {
+		return "", err
+	}
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(fname); err == nil {
+		mode = info.Mode().Perm()
+	}
+	w := bufio.NewWriter(f)
+	err = write(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Chmod(mode)
+	}
+	closeErr := f.Close() // Must be closed before Rename().
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// ReplaceFile will write the file using the 'write' function. The data is
+// written to a temporary file in the same directory that then replaces the
+// file, so the original file is left in place if anything fails.
+func ReplaceFile(fname string, write func(w *bufio.Writer) error) error {
+	temp, err := WriteTemp(fname, write)
+	if err != nil {
+		return err
+	}
+	if err = os.Rename(temp, fname); err != nil {
+		os.Remove(temp)
+		return err
+	}
+	return nil
+}
+
+// BackupFile will copy the file to a backup in the directory named with the
+// time (E.g., "eqclient.ini.20201019-210500.bak") and return the backup name.
+// The backup is written next to the file if the directory is "". An existing
+// backup is never replaced. A number is added to the name instead (E.g.,
+// "eqclient.ini.20201019-210500-2.bak").
+func BackupFile(fname string, dir string, now time.Time) (string, error) {
+	buf, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return "", err
+	}
+	if dir == "" {
+		dir = filepath.Dir(fname)
+	}
+	base := filepath.Join(dir, filepath.Base(fname)+now.Format(".20060102-150405"))
+	for n := 1; ; n++ {
+		bak := base + ".bak"
+		if n > 1 {
+			bak = fmt.Sprintf("%s-%d.bak", base, n)
+		}
+		f, err := os.OpenFile(bak, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		_, err = f.Write(buf)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		return bak, err
+	}
+}
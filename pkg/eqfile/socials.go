@@ -0,0 +1,211 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package eqfile
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Social is a social (macro) button from the "[Socials]" section of a
+// character UI file.
+type Social struct {
+	Page   int      // Page of the socials window (1-10)
+	Button int      // Button on the page (1-12)
+	Name   string   // Button label
+	Color  string   // Button color ("" if not set)
+	Lines  []string // Command lines (Up to 5)
+}
+
+// HotButton is a hot button from a "[HotButtons]" section of a character UI
+// file. The value is kept as EQ wrote it since it refers to other data such as
+// socials, spell gems, or items.
+type HotButton struct {
+	Bar    int // Hot button window (1 for "[HotButtons]", N for "[HotButtonsN]")
+	Page   int
+	Button int
+	Value  string
+}
+
+// Sections holding socials and hot buttons.
+const (
+	socialsSection    = "Socials"
+	hotButtonsSection = "HotButtons"
+)
+
+// socialLines is the number of command lines of a social.
+const socialLines = 5
+
+var (
+	socialKeyRe    = regexp.MustCompile(`(?i)^Page(\d+)Button(\d+)(Name|Color|Line(\d+))$`)
+	hotButtonKeyRe = regexp.MustCompile(`(?i)^Page(\d+)Button(\d+)$`)
+	hotButtonsRe   = regexp.MustCompile(`(?i)^HotButtons(\d*)$`)
+)
+
+// socialKey will return the key for a field of a social (E.g., "Name",
+// "Color", or "Line1").
+func socialKey(page int, button int, field string) string {
+	return fmt.Sprintf("Page%dButton%d%s", page, button, field)
+}
+
+// Socials will return the socials in the character UI file sorted by page and
+// button. A social is only returned if it has a name or a command line.
+func (ini *INI) Socials() []Social {
+	section := ini.Section(socialsSection)
+	if section == nil {
+		return nil
+	}
+	type pos struct{ Page, Button int }
+	byPos := make(map[pos]*Social)
+	var socials []*Social
+	for _, key := range section.Keys() {
+		m := socialKeyRe.FindStringSubmatch(key)
+		if m == nil {
+			continue
+		}
+		page, _ := strconv.Atoi(m[1])
+		button, _ := strconv.Atoi(m[2])
+		p := pos{page, button}
+		s, ok := byPos[p]
+		if !ok {
+			s = &Social{Page: page, Button: button, Lines: make([]string, socialLines)}
+			byPos[p] = s
+			socials = append(socials, s)
+		}
+		value, _ := section.Get(key)
+		switch {
+		case strings.EqualFold(m[3], "Name"):
+			s.Name = value
+		case strings.EqualFold(m[3], "Color"):
+			s.Color = value
+		default:
+			n, _ := strconv.Atoi(m[4])
+			if n >= 1 && n <= socialLines {
+				s.Lines[n-1] = value
+			}
+		}
+	}
+	var result []Social
+	for _, s := range socials {
+		// Trailing empty lines are not part of the social.
+		for len(s.Lines) > 0 && s.Lines[len(s.Lines)-1] == "" {
+			s.Lines = s.Lines[:len(s.Lines)-1]
+		}
+		if s.Name != "" || len(s.Lines) > 0 {
+			result = append(result, *s)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Page != result[j].Page {
+			return result[i].Page < result[j].Page
+		}
+		return result[i].Button < result[j].Button
+	})
+	return result
+}
+
+// SetSocial will replace the social at its page and button. Unused command
+// lines are removed.
+func (ini *INI) SetSocial(s Social) {
+	ini.DeleteSocial(s.Page, s.Button)
+	section := ini.AddSection(socialsSection)
+	section.Set(socialKey(s.Page, s.Button, "Name"), s.Name)
+	if s.Color != "" {
+		section.Set(socialKey(s.Page, s.Button, "Color"), s.Color)
+	}
+	for n, line := range s.Lines {
+		if n < socialLines && line != "" {
+			section.Set(socialKey(s.Page, s.Button, "Line"+strconv.Itoa(n+1)), line)
+		}
+	}
+}
+
+// DeleteSocial will remove the social at the page and button.
+func (ini *INI) DeleteSocial(page int, button int) {
+	section := ini.Section(socialsSection)
+	if section == nil {
+		return
+	}
+	for _, key := range section.Keys() {
+		m := socialKeyRe.FindStringSubmatch(key)
+		if m == nil {
+			continue
+		}
+		if p, _ := strconv.Atoi(m[1]); p != page {
+			continue
+		}
+		if b, _ := strconv.Atoi(m[2]); b != button {
+			continue
+		}
+		section.Delete(key)
+	}
+}
+
+// HotButtons will return the hot buttons of all hot button windows sorted by
+// window, page, and button. Buttons without a value are not returned.
+func (ini *INI) HotButtons() []HotButton {
+	var buttons []HotButton
+	for _, section := range ini.Sections {
+		m := hotButtonsRe.FindStringSubmatch(section.Name)
+		if m == nil {
+			continue
+		}
+		bar := 1
+		if m[1] != "" {
+			bar, _ = strconv.Atoi(m[1])
+		}
+		for _, key := range section.Keys() {
+			k := hotButtonKeyRe.FindStringSubmatch(key)
+			if k == nil {
+				continue
+			}
+			value, _ := section.Get(key)
+			if value == "" {
+				continue
+			}
+			page, _ := strconv.Atoi(k[1])
+			button, _ := strconv.Atoi(k[2])
+			buttons = append(buttons, HotButton{bar, page, button, value})
+		}
+	}
+	sort.Slice(buttons, func(i, j int) bool {
+		a, b := buttons[i], buttons[j]
+		if a.Bar != b.Bar {
+			return a.Bar < b.Bar
+		}
+		if a.Page != b.Page {
+			return a.Page < b.Page
+		}
+		return a.Button < b.Button
+	})
+	return buttons
+}
+
+// hotButtonSection will return the name of the section for the hot button
+// window.
+func hotButtonSection(bar int) string {
+	if bar == 1 {
+		return hotButtonsSection
+	}
+	return hotButtonsSection + strconv.Itoa(bar)
+}
+
+// hotButtonKey will return the key for the hot button.
+func hotButtonKey(page int, button int) string {
+	return fmt.Sprintf("Page%dButton%d", page, button)
+}
+
+// SetHotButton will set the hot button.
+func (ini *INI) SetHotButton(b HotButton) {
+	ini.Set(hotButtonSection(b.Bar), hotButtonKey(b.Page, b.Button), b.Value)
+}
+
+// DeleteHotButton will clear the hot button.
+func (ini *INI) DeleteHotButton(bar int, page int, button int) {
+	ini.Delete(hotButtonSection(bar), hotButtonKey(page, button))
+}
@@ -0,0 +1,118 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package eqfile
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Spell is the data found in each line of a Spellbook file.
+type Spell struct {
+	Level int    // Level the spell can be used at
+	Name  string // Spell name
+}
+
+// MissingSpell is the data found in each line of a MissingSpells file.
+type MissingSpell struct {
+	Level int    // Level the spell can be used at
+	Name  string // Spell name
+	Type  string // Source of the spell (E.g., Scroll, Song, Tome)
+}
+
+// Expected spellbook header line
+const spellbookHeader = "Level\tName"
+
+// Expected missing spells header line
+const missingSpellsHeader = "Level\tName\tType"
+
+// ReadSpellbook will return an array of Spells from the spellbook file.
+//
+// The format appears to be two columns (Level and Name) separated by a tab
+// character. The first line is a header line.
+//
+// This will do some simple checking of the file format and if any issues are
+// identified, it will return an error instead of any data.
+//
+// Error reasons:
+//   - File cannot be opened for reading.
+//   - Header line does not match expected header.
+//   - Column 1 is not an integer.
+func ReadSpellbook(fname string) ([]Spell, error) {
+	var spells []Spell
+	err := readSpellLines(fname, spellbookHeader, func(level int, parts []string) {
+		spells = append(spells, Spell{Level: level, Name: parts[1]})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return spells, nil
+}
+
+// ReadMissingSpells will return an array of MissingSpells from the missing
+// spells file.
+//
+// The format appears to be three columns (Level, Name, and Type) separated by
+// a tab character. The first line is a header line.
+//
+// This will do some simple checking of the file format and if any issues are
+// identified, it will return an error instead of any data.
+//
+// Error reasons:
+//   - File cannot be opened for reading.
+//   - Header line does not match expected header.
+//   - Column 1 is not an integer.
+func ReadMissingSpells(fname string) ([]MissingSpell, error) {
+	var spells []MissingSpell
+	err := readSpellLines(fname, missingSpellsHeader, func(level int, parts []string) {
+		spells = append(spells, MissingSpell{Level: level, Name: parts[1], Type: parts[2]})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return spells, nil
+}
+
+// readSpellLines will check the header of the file and pass the level and
+// columns of each line to 'add'. The number of columns is taken from the
+// header.
+func readSpellLines(fname string, header string, add func(level int, parts []string)) error {
+	f, err := os.Open(fname)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	// Check header line
+	_ = scanner.Scan()
+	head := scanner.Text()
+	if head != header {
+		// Unexpected header line. Either not the expected file type
+		// or format may have changed.
+		return fmt.Errorf("%s: missing expected header of \"%s\"",
+			fname, header)
+	}
+	cols := len(strings.Split(header, "\t"))
+	lineNo := 1
+	for scanner.Scan() {
+		line := scanner.Text()
+		lineNo++
+		parts := strings.Split(line, "\t")
+		if len(parts) != cols {
+			return fmt.Errorf("%s at line %d has %d columns instead of %d",
+				fname, lineNo, len(parts), cols)
+		}
+		level, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return fmt.Errorf("%s at line %d column 1 is not an integer",
+				fname, lineNo)
+		}
+		add(level, parts)
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,76 @@
+// Copyright 2020 Nuttann. All rights reserved.
+// The use of this source code is governed by an MIT license
+// that can be found in the LICENSE file.
+
+package eqfile
+
+import (
+	"os"
+	"sort"
+	"time"
+)
+
+// fileState is the size and modification time of a file when last checked.
+type fileState struct {
+	Size    int64
+	ModTime time.Time
+}
+
+// Watcher polls a set of files for changes. EQ writes the output files in one
+// pass, but a file could be read while EQ is still writing it. To avoid this,
+// a change is only reported once the file has the same size and modification
+// time for two consecutive polls.
+type Watcher struct {
+	known   map[string]fileState // State when last reported (or at start)
+	pending map[string]fileState // Changed state waiting to stop changing
+}
+
+// NewWatcher will return a Watcher for the files. The current state of the
+// files is taken as unchanged. Files that do not exist yet will be reported
+// once they are created.
+func NewWatcher(fnames []string) *Watcher {
+	w := &Watcher{
+		known:   make(map[string]fileState),
+		pending: make(map[string]fileState),
+	}
+	for _, fname := range fnames {
+		w.known[fname] = statFile(fname)
+	}
+	return w
+}
+
+// Changed will check all files and return the sorted names of the files that
+// changed since the last call and have stopped changing. This is intended to
+// be called periodically (E.g., every few seconds).
+func (w *Watcher) Changed() []string {
+	var changed []string
+	for fname, known := range w.known {
+		cur := statFile(fname)
+		if cur == known || cur == (fileState{}) {
+			// Unchanged or missing. A missing file is not reported so that
+			// a file being replaced is picked up once it exists again.
+			delete(w.pending, fname)
+			continue
+		}
+		if prev, ok := w.pending[fname]; ok && prev == cur {
+			// Same as the last poll, so it should be completely written.
+			delete(w.pending, fname)
+			w.known[fname] = cur
+			changed = append(changed, fname)
+			continue
+		}
+		w.pending[fname] = cur // Still changing or first time seen changed.
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// statFile will return the state of the file. A missing or unreadable file
+// returns the zero state.
+func statFile(fname string) fileState {
+	info, err := os.Stat(fname)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{Size: info.Size(), ModTime: info.ModTime()}
+}